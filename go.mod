@@ -5,13 +5,15 @@ go 1.23.0
 require (
 	github.com/Knetic/govaluate v3.0.0+incompatible
 	github.com/cgalvisleon/et v1.0.10
+	github.com/cgalvisleon/jdb v0.1.21
 	github.com/dop251/goja v0.0.0-20251121114222-56b1242a5f86
+	github.com/go-chi/chi/v5 v5.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/bwmarrin/snowflake v0.3.0 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
-	github.com/cgalvisleon/jdb v0.1.21 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/dlclark/regexp2 v1.11.4 // indirect
 	github.com/go-chi/chi v1.5.5 // indirect
@@ -1,5 +1,7 @@
 package vm
 
 const (
-	MSG_ARG_REQUIRED = "Argument is required (%s)"
+	MSG_ARG_REQUIRED       = "Argument is required (%s)"
+	MSG_DATABASE_NOT_FOUND = "Database not found (%s)"
+	MSG_MODEL_NOT_FOUND    = "Model not found (%s.%s)"
 )
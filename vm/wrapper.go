@@ -230,9 +230,14 @@ func Model(vm *Vm) {
 		}
 		database := args[0].String()
 		model := args[1].String()
-		result, err := jdb.GetModel(database, model)
-		if err != nil {
-			panic(vm.NewGoError(err))
+		db := jdb.GetDB(database)
+		if db == nil {
+			panic(vm.NewGoError(fmt.Errorf(MSG_DATABASE_NOT_FOUND, database)))
+		}
+
+		result := db.GetModel(model)
+		if result == nil {
+			panic(vm.NewGoError(fmt.Errorf(MSG_MODEL_NOT_FOUND, database, model)))
 		}
 
 		return vm.ToValue(result)
@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testCooperative
+* Step que espera hasta que se cancele el contexto de la instancia
+* @param started chan struct{}
+* @return FnContext
+**/
+func testCooperative(started chan struct{}) FnContext {
+	return func(flow *Instance, ctx et.Json) (et.Json, error) {
+		close(started)
+		<-flow.Context().Done()
+		return ctx, flow.Context().Err()
+	}
+}
+
+func TestCancelRunning(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	wf.newFlowFn("cancel_running", "v1", "Cancel", "", testCooperative(started), false, "test").
+		StepFn("End", "", testStep("end", true), false)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run("cancel-1", "cancel_running", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	if err := Cancel("cancel-1"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Fatal("the cancelled run must return the context error")
+	}
+
+	result := waitStatus(t, "cancel-1", FlowStatusCancelled)
+	if result.Ctx["end"] != nil {
+		t.Fatal("no step must run after the cancellation")
+	}
+
+	_, err := Continue("cancel-1", et.Json{}, et.Json{}, "test")
+	if err == nil || err.Error() != MSG_INSTANCE_CANCELLED {
+		t.Fatalf("a cancelled instance can not continue, got %v", err)
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	wf.newFlowFn("cancel_context", "v1", "Cancel", "", testCooperative(started), false, "test")
+
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := RunContext(c, "cancel-2", "cancel_context", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the context error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the run must return when its context is cancelled")
+	}
+	waitStatus(t, "cancel-2", FlowStatusCancelled)
+}
+
+func TestCancelWaiting(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSleepFlow(wf, time.Hour)
+
+	_, err := Run("cancel-3", "sleep", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waitStatus(t, "cancel-3", FlowStatusWaiting)
+
+	if err := Cancel("cancel-3"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	waitStatus(t, "cancel-3", FlowStatusCancelled)
+	wf.timers.mu.Lock()
+	_, pending := wf.timers.timers["cancel-3"]
+	wf.timers.mu.Unlock()
+	if pending {
+		t.Fatal("the sleep timer must be cancelled")
+	}
+}
@@ -0,0 +1,185 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/dop251/goja"
+)
+
+const (
+	ErrorCodeTimeout = "timeout"
+)
+
+const (
+	errorTypeStep        = "step"
+	errorTypeConflict    = "conflict"
+	errorTypeIdempotency = "idempotency"
+	errorTypeError       = "error"
+)
+
+type StepError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+/**
+* Error
+* @return string
+**/
+func (s *StepError) Error() string {
+	return s.Message
+}
+
+/**
+* NewError
+* Error con codigo, las funciones Go lo retornan para que retry y on_error lo identifiquen
+* @param code, format string, args ...any
+* @return error
+**/
+func NewError(code, format string, args ...any) error {
+	return &StepError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+/**
+* errorCode
+* Codigo de un StepError o del objeto lanzado con throw en una definition
+* @param err error
+* @return string
+**/
+func errorCode(err error) string {
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return stepErr.Code
+	}
+
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return ""
+	}
+
+	obj, ok := exception.Value().(*goja.Object)
+	if !ok {
+		return ""
+	}
+
+	code := obj.Get("code")
+	if code == nil || goja.IsUndefined(code) || goja.IsNull(code) {
+		return ""
+	}
+
+	return code.String()
+}
+
+type IdempotencyError struct {
+	Tag string `json:"tag"`
+	Key string `json:"key"`
+}
+
+/**
+* Error
+* @return string
+**/
+func (s *IdempotencyError) Error() string {
+	return fmt.Sprintf(MSG_IDEMPOTENCY_CONFLICT, s.Key, s.Tag)
+}
+
+type ConflictError struct {
+	InstanceId string `json:"instance_id"`
+	Expected   int64  `json:"expected"`
+	Current    int64  `json:"current"`
+}
+
+/**
+* Error
+* @return string
+**/
+func (s *ConflictError) Error() string {
+	return fmt.Sprintf(MSG_INSTANCE_CONFLICT, s.InstanceId, s.Expected, s.Current)
+}
+
+/**
+* encodeError
+* Conserva el tipo y el codigo del error para reconstruirlo con decodeError
+* @param err error
+* @return et.Json
+**/
+func encodeError(err error) et.Json {
+	if err == nil {
+		return nil
+	}
+
+	var stepErr *StepError
+	if errors.As(err, &stepErr) {
+		return et.Json{
+			"type":    errorTypeStep,
+			"code":    stepErr.Code,
+			"message": stepErr.Message,
+		}
+	}
+
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return et.Json{
+			"type":        errorTypeConflict,
+			"instance_id": conflict.InstanceId,
+			"expected":    conflict.Expected,
+			"current":     conflict.Current,
+		}
+	}
+
+	var idempotencyErr *IdempotencyError
+	if errors.As(err, &idempotencyErr) {
+		return et.Json{
+			"type": errorTypeIdempotency,
+			"tag":  idempotencyErr.Tag,
+			"key":  idempotencyErr.Key,
+		}
+	}
+
+	return et.Json{
+		"type":    errorTypeError,
+		"code":    errorCode(err),
+		"message": err.Error(),
+	}
+}
+
+/**
+* decodeError
+* Un error con codigo se reconstruye como StepError para que errorCode lo identifique
+* @param data et.Json
+* @return error
+**/
+func decodeError(data et.Json) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	switch data.Str("type") {
+	case errorTypeConflict:
+		return &ConflictError{
+			InstanceId: data.Str("instance_id"),
+			Expected:   data.Int64("expected"),
+			Current:    data.Int64("current"),
+		}
+	case errorTypeIdempotency:
+		return &IdempotencyError{
+			Tag: data.Str("tag"),
+			Key: data.Str("key"),
+		}
+	}
+
+	code := data.Str("code")
+	if data.Str("type") == errorTypeStep || code != "" {
+		return &StepError{
+			Code:    code,
+			Message: data.Str("message"),
+		}
+	}
+
+	return errors.New(data.Str("message"))
+}
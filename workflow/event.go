@@ -1,11 +1,12 @@
 package workflow
 
 const (
-	EVENT_ERROR           = "workflow:error"
-	EVENT_FLOW_SET        = "workflow:flow:set"
-	EVENT_FLOW_DELETE     = "workflow:flow:delete"
-	EVENT_FLOW_STATUS     = "workflow:flow:status"
-	EVENT_WORKFLOW_SET    = "workflow:set"
-	EVENT_WORKFLOW_DELETE = "workflow:delete"
-	EVENT_WORKFLOW_STATUS = "workflow:status"
+	EVENT_ERROR            = "workflow:error"
+	EVENT_FLOW_SET         = "workflow:flow:set"
+	EVENT_FLOW_DELETE      = "workflow:flow:delete"
+	EVENT_FLOW_STATUS      = "workflow:flow:status"
+	EVENT_WORKFLOW_SET     = "workflow:set"
+	EVENT_WORKFLOW_DELETE  = "workflow:delete"
+	EVENT_WORKFLOW_STATUS  = "workflow:status"
+	EVENT_WORKFLOW_HISTORY = "workflow:history"
 )
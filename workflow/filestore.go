@@ -0,0 +1,524 @@
+package workflow
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cgalvisleon/et/logs"
+)
+
+type logEntry struct {
+	Result  *Result       `json:"result,omitempty"`
+	History *HistoryEntry `json:"history,omitempty"`
+}
+
+/**
+* FileStore
+* Store en disco: flows/<tag>.json, instances/<id>.json con la ultima version de la instancia
+* e instances/<id>.log con los resultados y el historial, una linea json por registro
+**/
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+/**
+* NewFileStore
+* @param dir string
+* @return *FileStore, error
+**/
+func NewFileStore(dir string) (*FileStore, error) {
+	result := &FileStore{
+		dir: dir,
+		mu:  sync.Mutex{},
+	}
+
+	for _, sub := range []string{"flows", "instances"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* path
+* Los nombres se escapan porque tags e ids pueden tener separadores
+* @param sub, name, ext string
+* @return string
+**/
+func (s *FileStore) path(sub, name, ext string) string {
+	return filepath.Join(s.dir, sub, url.PathEscape(name)+ext)
+}
+
+/**
+* write
+* Escribe en un archivo temporal, fsync y rename para que el archivo nunca quede a medias, requiere mu
+* @param path string, bt []byte
+* @return error
+**/
+func (s *FileStore) write(path string, bt []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(bt)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return err
+	}
+
+	return s.syncDir(filepath.Dir(path))
+}
+
+/**
+* syncDir
+* @param dir string
+* @return error
+**/
+func (s *FileStore) syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
+
+/**
+* append
+* Escribe los resultados y el historial en una sola escritura, requiere mu
+* @param instanceId string, changes *Changes
+* @return error
+**/
+func (s *FileStore) append(instanceId string, changes *Changes) error {
+	entries := make([]*logEntry, 0, len(changes.Results)+len(changes.History))
+	for _, result := range changes.Results {
+		entries = append(entries, &logEntry{Result: result})
+	}
+	for _, entry := range changes.History {
+		entries = append(entries, &logEntry{History: entry})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	bt := make([]byte, 0)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		bt = append(append(bt, line...), '\n')
+	}
+
+	f, err := os.OpenFile(s.path("instances", instanceId, ".log"), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = s.repair(f, instanceId)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(bt)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+/**
+* repair
+* Corta la ultima linea incompleta de una escritura interrumpida para que la siguiente
+* escritura no quede pegada a ella, requiere mu
+* @param f *os.File, instanceId string
+* @return error
+**/
+func (s *FileStore) repair(f *os.File, instanceId string) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	end := size
+	buf := make([]byte, 4096)
+	for end > 0 {
+		start := max(end-int64(len(buf)), 0)
+		n, err := f.ReadAt(buf[:end-start], start)
+		if err != nil {
+			return err
+		}
+
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = start + int64(i) + 1
+			break
+		}
+		end = start
+	}
+
+	if end == size {
+		return nil
+	}
+
+	logs.Logf(packageName, MSG_STORE_LOG_REPAIRED, instanceId, size-end)
+	return f.Truncate(end)
+}
+
+/**
+* entries
+* Solo se ignora una ultima linea incompleta, la de una escritura interrumpida;
+* una linea corrupta en medio del log es un error
+* @param instanceId string
+* @return []*logEntry, error
+**/
+func (s *FileStore) entries(instanceId string) ([]*logEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path("instances", instanceId, ".log"))
+	if errors.Is(err, os.ErrNotExist) {
+		return []*logEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := make([]*logEntry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	var corrupt error
+	for scanner.Scan() {
+		line++
+		if corrupt != nil {
+			return nil, fmt.Errorf(MSG_STORE_LOG_CORRUPT, instanceId, line-1, corrupt)
+		}
+
+		var entry *logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			corrupt = err
+			continue
+		}
+
+		result = append(result, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if corrupt != nil {
+		logs.Logf(packageName, MSG_STORE_LOG_TRUNCATED, instanceId, line)
+	}
+
+	return result, nil
+}
+
+/**
+* names
+* @param sub, ext string
+* @return []string, error
+**/
+func (s *FileStore) names(sub, ext string) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), ext)
+		if !ok || file.IsDir() {
+			continue
+		}
+
+		name, err = url.PathUnescape(name)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, name)
+	}
+	slices.Sort(result)
+
+	return result, nil
+}
+
+/**
+* GetFlow
+* @param tag string
+* @return *Flow, error
+**/
+func (s *FileStore) GetFlow(tag string) (*Flow, error) {
+	bt, err := os.ReadFile(s.path("flows", tag, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSpec(bt)
+}
+
+/**
+* SetFlow
+* @param flow *Flow
+* @return error
+**/
+func (s *FileStore) SetFlow(flow *Flow) error {
+	bt, err := flow.Serialize()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(s.path("flows", flow.Tag, ".json"), bt)
+}
+
+/**
+* DeleteFlow
+* @param tag string
+* @return error
+**/
+func (s *FileStore) DeleteFlow(tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path("flows", tag, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+/**
+* ListFlows
+* @return []*Flow, error
+**/
+func (s *FileStore) ListFlows() ([]*Flow, error) {
+	tags, err := s.names("flows", ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Flow, 0, len(tags))
+	for _, tag := range tags {
+		flow, err := s.GetFlow(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, flow)
+	}
+
+	return result, nil
+}
+
+/**
+* GetInstance
+* @param id string
+* @return *Instance, error
+**/
+func (s *FileStore) GetInstance(id string) (*Instance, error) {
+	bt, err := os.ReadFile(s.path("instances", id, ".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errorInstanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result *Instance
+	err = json.Unmarshal(bt, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/**
+* revision
+* @param path string
+* @return int64, error
+**/
+func (s *FileStore) revision(path string) (int64, error) {
+	bt, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var current struct {
+		Revision int64 `json:"revision"`
+	}
+	err = json.Unmarshal(bt, &current)
+	if err != nil {
+		return 0, err
+	}
+
+	return current.Revision, nil
+}
+
+/**
+* SetInstance
+* El log se escribe antes que la instancia
+* @param instance *Instance, revision int64, changes *Changes
+* @return error
+**/
+func (s *FileStore) SetInstance(instance *Instance, revision int64, changes *Changes) error {
+	bt, err := instance.Serialize()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path("instances", instance.Id, ".json")
+	current, err := s.revision(path)
+	if err != nil {
+		return err
+	}
+
+	if current != revision {
+		return &ConflictError{InstanceId: instance.Id, Expected: revision, Current: current}
+	}
+
+	err = s.append(instance.Id, changes)
+	if err != nil {
+		return err
+	}
+
+	return s.write(path, bt)
+}
+
+/**
+* DeleteInstance
+* @param id string
+* @return error
+**/
+func (s *FileStore) DeleteInstance(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ext := range []string{".json", ".log"} {
+		err := os.Remove(s.path("instances", id, ext))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/**
+* ListInstances
+* @param query Query
+* @return []*Instance, error
+**/
+func (s *FileStore) ListInstances(query Query) ([]*Instance, error) {
+	ids, err := s.names("instances", ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Instance, 0)
+	for _, id := range ids {
+		instance, err := s.GetInstance(id)
+		if err != nil {
+			continue
+		}
+
+		if !query.match(instance) {
+			continue
+		}
+
+		result = append(result, instance)
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* GetResults
+* @param instanceId string
+* @return []*Result, error
+**/
+func (s *FileStore) GetResults(instanceId string) ([]*Result, error) {
+	entries, err := s.entries(instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Result, 0)
+	for _, entry := range entries {
+		if entry.Result != nil {
+			result = append(result, entry.Result)
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* GetHistory
+* @param instanceId string
+* @return []*HistoryEntry, error
+**/
+func (s *FileStore) GetHistory(instanceId string) ([]*HistoryEntry, error) {
+	entries, err := s.entries(instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*HistoryEntry, 0)
+	for _, entry := range entries {
+		if entry.History != nil {
+			result = append(result, entry.History)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"os"
+	"testing"
+)
+
+/**
+* testFileStore
+* @param t *testing.T
+* @return *FileStore
+**/
+func testFileStore(t *testing.T) *FileStore {
+	t.Helper()
+
+	result, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+
+	return result
+}
+
+func TestFileStoreRevision(t *testing.T) {
+	testStoreRevision(t, testFileStore(t))
+}
+
+func TestFileStoreList(t *testing.T) {
+	testStoreList(t, testFileStore(t))
+}
+
+func TestFileStoreHistory(t *testing.T) {
+	testStoreHistory(t, testFileStore(t))
+}
+
+/**
+* appendRaw
+* Agrega bytes al log de la instancia como lo dejaria una escritura interrumpida
+* @param t *testing.T, store *FileStore, instanceId, data string
+**/
+func appendRaw(t *testing.T, store *FileStore, instanceId, data string) {
+	t.Helper()
+
+	f, err := os.OpenFile(store.path("instances", instanceId, ".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("write log: %v", err)
+	}
+}
+
+/**
+* appendHistory
+* @param t *testing.T, store *FileStore, instance *Instance, tp TpHistory
+**/
+func appendHistory(t *testing.T, store *FileStore, instance *Instance, tp TpHistory) {
+	t.Helper()
+
+	err := saveChanges(store, instance, &Changes{
+		History: []*HistoryEntry{{InstanceId: instance.Id, Type: tp}},
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func TestFileStoreTruncatedLog(t *testing.T) {
+	store := testFileStore(t)
+	appendHistory(t, store, testInstance("log", "orders", FlowStatusRunning), HistoryCreated)
+	appendRaw(t, store, "log", `{"history":{"instance_id":"log","ty`)
+
+	result, err := store.GetHistory("log")
+	if err != nil {
+		t.Fatalf("trailing partial line must be ignored, got %v", err)
+	}
+	if len(result) != 1 || result[0].Type != HistoryCreated {
+		t.Fatalf("expected the created entry, got %d entries", len(result))
+	}
+}
+
+func TestFileStoreAppendAfterTruncatedLog(t *testing.T) {
+	store := testFileStore(t)
+	instance := testInstance("log", "orders", FlowStatusRunning)
+	appendHistory(t, store, instance, HistoryCreated)
+	appendRaw(t, store, "log", `{"history":{"instance_id":"log","ty`)
+	appendHistory(t, store, instance, HistoryStepStarted)
+
+	result, err := store.GetHistory("log")
+	if err != nil {
+		t.Fatalf("partial line must be cut before appending, got %v", err)
+	}
+	if len(result) != 2 || result[0].Type != HistoryCreated || result[1].Type != HistoryStepStarted {
+		t.Fatalf("expected created and step_started, got %d entries", len(result))
+	}
+}
+
+func TestFileStoreCorruptLog(t *testing.T) {
+	store := testFileStore(t)
+	instance := testInstance("log", "orders", FlowStatusRunning)
+	appendHistory(t, store, instance, HistoryCreated)
+	appendRaw(t, store, "log", "not json\n")
+	appendHistory(t, store, instance, HistoryStepStarted)
+
+	if _, err := store.GetHistory("log"); err == nil {
+		t.Fatal("corrupt line in the middle of the log must fail")
+	}
+	if _, err := store.GetResults("log"); err == nil {
+		t.Fatal("corrupt line in the middle of the log must fail")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/cgalvisleon/et/et"
@@ -42,6 +43,7 @@ var (
 **/
 func OnGetFlow(f GetFlowFn) {
 	getFlow = f
+	useHooks()
 }
 
 /**
@@ -51,6 +53,7 @@ func OnGetFlow(f GetFlowFn) {
 **/
 func OnSetFlow(f SetFlowFn) {
 	setFlow = f
+	useHooks()
 }
 
 /**
@@ -60,6 +63,7 @@ func OnSetFlow(f SetFlowFn) {
 **/
 func OnDeleteFlow(f DeleteFlowFn) {
 	deleteFlow = f
+	useHooks()
 }
 
 type Model struct {
@@ -68,6 +72,7 @@ type Model struct {
 }
 
 type Flow struct {
+	Spec          string                `json:"spec"`
 	Tag           string                `json:"tag"`
 	Version       string                `json:"version"`
 	Name          string                `json:"name"`
@@ -83,6 +88,8 @@ type Flow struct {
 	Models        []*Model              `json:"models"`
 	models        map[string]*jdb.Model `json:"-"`
 	isDebug       bool                  `json:"-"`
+	resolved      bool                  `json:"-"`
+	mu            sync.Mutex            `json:"-"`
 }
 
 /**
@@ -122,15 +129,13 @@ func (s *Flow) ToJson() et.Json {
 * @return error
 **/
 func (s *Flow) Save() error {
-	if setFlow != nil {
-		err := setFlow(s)
-		if err != nil {
-			err = fmt.Errorf("setFlow: error on save flow: %s, error: %v", s.Tag, err)
-			event.Publish(EVENT_ERROR, et.Json{
-				"message": err.Error(),
-			})
-			return err
-		}
+	err := getStore().SetFlow(s)
+	if err != nil {
+		err = fmt.Errorf("setFlow: error on save flow: %s, error: %v", s.Tag, err)
+		event.Publish(EVENT_ERROR, et.Json{
+			"message": err.Error(),
+		})
+		return err
 	}
 	event.Publish(EVENT_FLOW_SET, s.ToJson())
 	return nil
@@ -141,6 +146,10 @@ func (s *Flow) Save() error {
 * @return error
 **/
 func (s *Flow) setConfig(format string, args ...any) {
+	s.mu.Lock()
+	s.resolved = false
+	s.mu.Unlock()
+
 	logs.Logf(packageName, format, args...)
 	s.Save()
 }
@@ -168,6 +177,22 @@ func (s *Flow) StepFn(name, description string, fn FnContext, stop bool) *Flow {
 	return s
 }
 
+/**
+* StepFunction
+* Usa una funcion registrada con RegisterFn, a diferencia de StepFn se conserva al exportar el flujo
+* @param name, description string, function string, stop bool
+* @return *Flow
+**/
+func (s *Flow) StepFunction(name, description string, function string, stop bool) *Flow {
+	result, _ := newStepFn(name, description, getFunction(function), stop)
+	result.Function = function
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_STEP_CREATED, n, name, s.Tag)
+
+	return s
+}
+
 /**
 * Step
 * @param name, description string, definition string, stop bool
@@ -201,19 +226,248 @@ func (s *Flow) StepByFile(name, description string, filePath string, stop bool)
 	return s.Step(name, description, string(definition), stop)
 }
 
+/**
+* Parallel
+* @param name, description string, wait int, merge TpMerge, stop bool
+* @return *Flow
+**/
+func (s *Flow) Parallel(name, description string, wait int, merge TpMerge, stop bool) *Flow {
+	result, _ := newStepParallel(name, description, wait, merge, stop)
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_PARALLEL_CREATED, n, name, wait, result.Merge, s.Tag)
+
+	return s
+}
+
+/**
+* addBranch
+* @param branch *Step
+* @return *Flow
+**/
+func (s *Flow) addBranch(branch *Step) *Flow {
+	n := len(s.Steps)
+	if n == 0 || s.Steps[n-1].Type != TpParallel {
+		logs.Errorf(MSG_INSTANCE_NOT_PARALLEL, n-1, branch.Name)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Branches = append(step.Branches, branch)
+	s.setConfig(MSG_INSTANCE_BRANCH_CREATED, branch.Name, n-1, step.Name, s.Tag)
+
+	return s
+}
+
+/**
+* BranchFn
+* @param name, description string, fn FnContext, rollback FnContext
+* @return *Flow
+**/
+func (s *Flow) BranchFn(name, description string, fn FnContext, rollback FnContext) *Flow {
+	result, _ := newStepFn(name, description, fn, false)
+	result.rollbacks = rollback
+
+	return s.addBranch(result)
+}
+
+/**
+* BranchFunction
+* @param name, description string, function, rollback string
+* @return *Flow
+**/
+func (s *Flow) BranchFunction(name, description string, function, rollback string) *Flow {
+	result, _ := newStepFn(name, description, getFunction(function), false)
+	result.Function = function
+	if rollback != "" {
+		result.rollbacks = getFunction(rollback)
+		result.RollbackFunction = rollback
+	}
+
+	return s.addBranch(result)
+}
+
+/**
+* Branch
+* @param name, description string, definition string
+* @return *Flow
+**/
+func (s *Flow) Branch(name, description string, definition string) *Flow {
+	result, _ := newStepDefinition(name, description, definition, false)
+
+	return s.addBranch(result)
+}
+
+/**
+* ForEach
+* Itera el arreglo en la ruta path del ctx, el item y su indice quedan en ctx y en la vm como item e index
+* @param name, description, path string, concurrency int, stopOnError, stop bool
+* @return *Flow
+**/
+func (s *Flow) ForEach(name, description, path string, concurrency int, stopOnError, stop bool) *Flow {
+	result, _ := newStepForEach(name, description, path, concurrency, stopOnError, stop)
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_FOREACH_CREATED, n, name, path, result.Concurrency, stopOnError, s.Tag)
+
+	return s
+}
+
+/**
+* addBody
+* @param body *Step
+* @return *Flow
+**/
+func (s *Flow) addBody(body *Step) *Flow {
+	n := len(s.Steps)
+	if n == 0 || s.Steps[n-1].Type != TpForEach {
+		logs.Errorf(MSG_INSTANCE_NOT_FOREACH, n-1, body.Name)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Body = append(step.Body, body)
+	s.setConfig(MSG_INSTANCE_BODY_CREATED, body.Name, n-1, step.Name, s.Tag)
+
+	return s
+}
+
+/**
+* EachFn
+* @param name, description string, fn FnContext
+* @return *Flow
+**/
+func (s *Flow) EachFn(name, description string, fn FnContext) *Flow {
+	result, _ := newStepFn(name, description, fn, false)
+
+	return s.addBody(result)
+}
+
+/**
+* EachFunction
+* @param name, description string, function string
+* @return *Flow
+**/
+func (s *Flow) EachFunction(name, description string, function string) *Flow {
+	result, _ := newStepFn(name, description, getFunction(function), false)
+	result.Function = function
+
+	return s.addBody(result)
+}
+
+/**
+* Each
+* @param name, description string, definition string
+* @return *Flow
+**/
+func (s *Flow) Each(name, description string, definition string) *Flow {
+	result, _ := newStepDefinition(name, description, definition, false)
+
+	return s.addBody(result)
+}
+
+/**
+* SubFlow
+* Inicia el flujo tag como instancia hija, mapping indica llave del hijo -> ruta en el ctx del padre
+* @param name, description, tag string, mapping map[string]string, stop bool
+* @return *Flow
+**/
+func (s *Flow) SubFlow(name, description, tag string, mapping map[string]string, stop bool) *Flow {
+	result, _ := newStepSubFlow(name, description, tag, mapping, stop)
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_SUBFLOW_CREATED, n, name, tag, s.Tag)
+
+	return s
+}
+
+/**
+* WaitSignal
+* La instancia queda en espera hasta recibir la señal signal con workflow.Signal, si no llega
+* antes de timeout se va al step timeoutGoTo, sin timeoutGoTo el step falla
+* @param name, description, signal string, timeout time.Duration, timeoutGoTo string
+* @return *Flow
+**/
+func (s *Flow) WaitSignal(name, description, signal string, timeout time.Duration, timeoutGoTo string) *Flow {
+	result, _ := newStepSignal(name, description, signal, timeout, timeoutGoTo)
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_SIGNAL_CREATED, n, name, signal, timeout, s.Tag)
+
+	return s
+}
+
+/**
+* Sleep
+* @param name, description string, duration time.Duration
+* @return *Flow
+**/
+func (s *Flow) Sleep(name, description string, duration time.Duration) *Flow {
+	result, _ := newStepSleep(name, description, duration, "")
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_SLEEP_CREATED, n, name, duration, s.Tag)
+
+	return s
+}
+
+/**
+* SleepUntil
+* La expresion se evalua contra el ctx y debe retornar una fecha RFC3339 o un unix en segundos
+* @param name, description string, expression string
+* @return *Flow
+**/
+func (s *Flow) SleepUntil(name, description string, expression string) *Flow {
+	result, _ := newStepSleep(name, description, 0, expression)
+	s.Steps = append(s.Steps, result)
+	n := len(s.Steps)
+	s.setConfig(MSG_INSTANCE_SLEEP_UNTIL_CREATED, n, name, expression, s.Tag)
+
+	return s
+}
+
+/**
+* getModel
+* Sin database el modelo se busca en la primera base de datos conectada
+* @param database, name string
+* @return *jdb.Model, error
+**/
+func getModel(database, name string) (*jdb.Model, error) {
+	var result *jdb.Model
+	if database == "" {
+		result = jdb.GetModel(name)
+	} else if db := jdb.GetDB(database); db != nil {
+		result = db.GetModel(name)
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf(MSG_MODEL_NOT_FOUND, database, name)
+	}
+
+	return result, nil
+}
+
 /**
 * AddModel
 * @param database, name string
 * @return *Flow
 **/
 func (s *Flow) AddModel(database, name string) *Flow {
-	model, err := jdb.GetModel(database, name)
+	model, err := getModel(database, name)
 	if err != nil {
 		logs.Error(err)
 		return s
 	}
 
+	if s.models == nil {
+		s.models = make(map[string]*jdb.Model)
+	}
 	s.models[name] = model
+	s.Models = append(s.Models, &Model{
+		Database: database,
+		Name:     name,
+	})
+
 	return s
 }
 
@@ -224,13 +478,220 @@ func (s *Flow) AddModel(database, name string) *Flow {
 **/
 func (s *Flow) Rollback(fn FnContext) *Flow {
 	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
 	step := s.Steps[n-1]
 	step.rollbacks = fn
+	step.RollbackFunction = ""
+	step.RollbackDefinition = ""
+	s.setConfig(MSG_INSTANCE_ROLLBACK_CREATED, n-1, step.Name, s.Tag)
+
+	return s
+}
+
+/**
+* RollbackFunction
+* @param function string
+* @return *Flow
+**/
+func (s *Flow) RollbackFunction(function string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.rollbacks = getFunction(function)
+	step.RollbackFunction = function
+	step.RollbackDefinition = ""
 	s.setConfig(MSG_INSTANCE_ROLLBACK_CREATED, n-1, step.Name, s.Tag)
 
 	return s
 }
 
+/**
+* RollbackDefinition
+* @param definition string
+* @return *Flow
+**/
+func (s *Flow) RollbackDefinition(definition string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.RollbackFunction = ""
+	step.RollbackDefinition = definition
+	step.rollbacks = step.runRollbackScript
+	s.setConfig(MSG_INSTANCE_ROLLBACK_CREATED, n-1, step.Name, s.Tag)
+
+	return s
+}
+
+/**
+* RollbackByFile
+* @param filePath string
+* @return *Flow
+**/
+func (s *Flow) RollbackByFile(filePath string) *Flow {
+	definition, err := os.ReadFile(filePath)
+	if err != nil {
+		logs.Error(err)
+		definition = []byte("")
+	}
+
+	return s.RollbackDefinition(string(definition))
+}
+
+/**
+* Input
+* Selecciona lo que ve el ultimo step definido, llave destino y ruta del ctx, ej: {"customer": "ctx.invoice.customer"}
+* @param input map[string]string
+* @return *Flow
+**/
+func (s *Flow) Input(input map[string]string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Input = input
+	s.setConfig(MSG_INSTANCE_INPUT_CREATED, n-1, step.Name, input, s.Tag)
+
+	return s
+}
+
+/**
+* Output
+* Donde se escribe el resultado del ultimo step definido, ruta del ctx y ruta del resultado, ej: {"ctx.customer": ""}
+* @param output map[string]string
+* @return *Flow
+**/
+func (s *Flow) Output(output map[string]string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Output = output
+	s.setConfig(MSG_INSTANCE_OUTPUT_CREATED, n-1, step.Name, output, s.Tag)
+
+	return s
+}
+
+/**
+* OnError
+* Si el error del ultimo step coincide por codigo o patron continua en el step goToStep en lugar del rollback,
+* con expose el error queda en el ctx como error
+* @param code, pattern, goToStep string, expose bool
+* @return *Flow
+**/
+func (s *Flow) OnError(code, pattern, goToStep string, expose bool) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	if goToStep == "" {
+		logs.Errorf(MSG_ON_ERROR_WITHOUT_TARGET, step.Name, s.Tag)
+		return s
+	}
+
+	step.OnError = append(step.OnError, &OnError{
+		Code:     code,
+		Pattern:  pattern,
+		GoTo:     -1,
+		GoToStep: goToStep,
+		Expose:   expose,
+	})
+	s.setConfig(MSG_INSTANCE_ON_ERROR_CREATED, n-1, step.Name, goToStep, s.Tag)
+
+	return s
+}
+
+/**
+* Timeout
+* Limita el tiempo de ejecucion del ultimo step definido
+* @param timeout time.Duration
+* @return *Flow
+**/
+func (s *Flow) Timeout(timeout time.Duration) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Timeout = timeout
+	s.setConfig(MSG_INSTANCE_TIMEOUT_CREATED, n-1, step.Name, timeout, s.Tag)
+
+	return s
+}
+
+/**
+* Retry
+* Politica de reintentos del ultimo step definido, sin codigos todos los errores se reintentan
+* @param maxAttempts int, delay time.Duration, multiplier float64, maxDelay time.Duration, jitter float64, codes ...string
+* @return *Flow
+**/
+func (s *Flow) Retry(maxAttempts int, delay time.Duration, multiplier float64, maxDelay time.Duration, jitter float64, codes ...string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.Retry = &Retry{
+		MaxAttempts: maxAttempts,
+		Delay:       delay,
+		Multiplier:  multiplier,
+		MaxDelay:    maxDelay,
+		Jitter:      jitter,
+		Codes:       codes,
+	}
+	s.setConfig(MSG_INSTANCE_RETRY_CREATED, n-1, step.Name, maxAttempts, s.Tag)
+
+	return s
+}
+
+/**
+* RetryIf
+* Predicado que decide si un error del ultimo step se reintenta, no se exporta en la especificacion
+* @param fn func(error) bool
+* @return *Flow
+**/
+func (s *Flow) RetryIf(fn func(error) bool) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	if step.Retry == nil {
+		logs.Errorf(MSG_VALIDATE_REQUIRED, "retry")
+		return s
+	}
+
+	step.Retry.retryable = fn
+
+	return s
+}
+
 /**
 * Consistency
 * @param consistency TpConsistency
@@ -281,6 +742,11 @@ func (s *Flow) Retention(retentionTime time.Duration) *Flow {
 **/
 func (s *Flow) IfElse(expression string, yesGoTo int, noGoTo int) *Flow {
 	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
 	step := s.Steps[n-1]
 	step.ifElse(expression, yesGoTo, noGoTo)
 	s.setConfig(MSG_INSTANCE_IFELSE, n-1, step.Name, expression, yesGoTo, noGoTo, s.Tag)
@@ -288,6 +754,183 @@ func (s *Flow) IfElse(expression string, yesGoTo int, noGoTo int) *Flow {
 	return s
 }
 
+/**
+* IfElseByName
+* Los nombres se resuelven a indices una sola vez por definicion, al validar o al crear la primera instancia,
+* por lo que pueden referenciar steps que se definen despues
+* @param expression string, yesGoTo, noGoTo string
+* @return *Flow
+**/
+func (s *Flow) IfElseByName(expression string, yesGoTo, noGoTo string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.ifElseByName(expression, yesGoTo, noGoTo)
+	step.YesGoTo = s.IndexOf(yesGoTo)
+	step.NoGoTo = s.IndexOf(noGoTo)
+	s.setConfig(MSG_INSTANCE_IFELSE_BY_NAME, n-1, step.Name, expression, yesGoTo, noGoTo, s.Tag)
+
+	return s
+}
+
+/**
+* Switch
+* El resultado de la expresion se compara con las llaves de cases, si ninguna coincide
+* se va a defaultGoTo, con -1 se continua al siguiente step
+* @param expression string, cases map[string]int, defaultGoTo int
+* @return *Flow
+**/
+func (s *Flow) Switch(expression string, cases map[string]int, defaultGoTo int) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.switchCases(expression, cases, defaultGoTo)
+	s.setConfig(MSG_INSTANCE_SWITCH, n-1, step.Name, expression, len(cases), defaultGoTo, s.Tag)
+
+	return s
+}
+
+/**
+* SwitchByName
+* @param expression string, cases map[string]string, defaultGoTo string
+* @return *Flow
+**/
+func (s *Flow) SwitchByName(expression string, cases map[string]string, defaultGoTo string) *Flow {
+	n := len(s.Steps)
+	if n == 0 {
+		logs.Errorf(MSG_FLOW_WITHOUT_STEPS, s.Tag)
+		return s
+	}
+
+	step := s.Steps[n-1]
+	step.switchCasesByName(expression, cases, defaultGoTo)
+	s.setConfig(MSG_INSTANCE_SWITCH_BY_NAME, n-1, step.Name, expression, len(cases), defaultGoTo, s.Tag)
+
+	return s
+}
+
+/**
+* IndexOf
+* @param name string
+* @return int
+**/
+func (s *Flow) IndexOf(name string) int {
+	for i, step := range s.Steps {
+		if step.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+/**
+* resolve
+* Valida que los nombres de los steps sean unicos y resuelve los gotos por nombre a indices
+* @return error
+**/
+func (s *Flow) resolve() error {
+	names := make(map[string]bool)
+	for _, step := range s.Steps {
+		if names[step.Name] {
+			return fmt.Errorf(MSG_STEP_DUPLICATED, step.Name, s.Tag)
+		}
+		names[step.Name] = true
+	}
+
+	for _, step := range s.Steps {
+		if step.YesGoToStep != "" {
+			idx := s.IndexOf(step.YesGoToStep)
+			if idx == -1 {
+				return fmt.Errorf(MSG_STEP_NOT_FOUND, step.YesGoToStep, s.Tag)
+			}
+			step.YesGoTo = idx
+		}
+
+		if step.NoGoToStep != "" {
+			idx := s.IndexOf(step.NoGoToStep)
+			if idx == -1 {
+				return fmt.Errorf(MSG_STEP_NOT_FOUND, step.NoGoToStep, s.Tag)
+			}
+			step.NoGoTo = idx
+		}
+
+		if len(step.CasesStep) > 0 {
+			step.Cases = make(map[string]int)
+			for key, name := range step.CasesStep {
+				idx := s.IndexOf(name)
+				if idx == -1 {
+					return fmt.Errorf(MSG_STEP_NOT_FOUND, name, s.Tag)
+				}
+				step.Cases[key] = idx
+			}
+		}
+
+		if step.TimeoutGoToStep != "" {
+			idx := s.IndexOf(step.TimeoutGoToStep)
+			if idx == -1 {
+				return fmt.Errorf(MSG_STEP_NOT_FOUND, step.TimeoutGoToStep, s.Tag)
+			}
+			step.TimeoutGoTo = idx
+		}
+
+		if step.DefaultGoToStep != "" {
+			idx := s.IndexOf(step.DefaultGoToStep)
+			if idx == -1 {
+				return fmt.Errorf(MSG_STEP_NOT_FOUND, step.DefaultGoToStep, s.Tag)
+			}
+			step.DefaultGoTo = idx
+		}
+
+		for _, handler := range step.OnError {
+			if handler.GoToStep == "" {
+				if handler.GoTo == -1 {
+					return fmt.Errorf(MSG_ON_ERROR_WITHOUT_TARGET, step.Name, s.Tag)
+				}
+				continue
+			}
+
+			idx := s.IndexOf(handler.GoToStep)
+			if idx == -1 {
+				return fmt.Errorf(MSG_STEP_NOT_FOUND, handler.GoToStep, s.Tag)
+			}
+			handler.GoTo = idx
+		}
+	}
+
+	return nil
+}
+
+/**
+* prepare
+* Resuelve los nombres una sola vez por definicion, la definicion cambia solo con los builders
+* @return error
+**/
+func (s *Flow) prepare() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resolved {
+		return nil
+	}
+
+	err := s.resolve()
+	if err != nil {
+		return err
+	}
+
+	s.resolved = true
+	return nil
+}
+
 /**
 * newFlow
 * @param tag, version, name, description string, createdBy string
@@ -303,6 +946,7 @@ func newFlow(tag, version, name, description string, createdBy string) *Flow {
 		RetentionTime: 15 * time.Minute,
 		Steps:         make([]*Step, 0),
 		CreatedBy:     createdBy,
+		models:        make(map[string]*jdb.Model),
 	}
 
 	return result
@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* newStepForEach
+* @param name, description, path string, concurrency int, stopOnError, stop bool
+* @return *Step
+**/
+func newStepForEach(name, description, path string, concurrency int, stopOnError, stop bool) (*Step, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	result := &Step{
+		Name:        name,
+		Description: description,
+		Type:        TpForEach,
+		Stop:        stop,
+		Path:        path,
+		Concurrency: concurrency,
+		StopOnError: stopOnError,
+		Body:        make([]*Step, 0),
+	}
+	result.fn = result.runForEach
+
+	return result, nil
+}
+
+/**
+* runItem
+* Ejecuta la secuencia del body para un item, cada step recibe el ctx acumulado
+* @param flow *Instance, ctx et.Json, index int, item interface{}
+* @return et.Json, error
+**/
+func (s *Step) runItem(flow *Instance, ctx et.Json, index int, item interface{}) (et.Json, error) {
+	vars := et.Json{
+		"item":  item,
+		"index": index,
+	}
+	itemCtx := ctx.Clone()
+	for k, v := range vars {
+		itemCtx[k] = v
+	}
+
+	result := et.Json{}
+	for _, body := range s.Body {
+		res, err := body.runIsolated(flow, itemCtx.Clone(), vars)
+		if err != nil {
+			return res, err
+		}
+
+		result = res
+		for k, v := range res {
+			itemCtx[k] = v
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* runForEach
+* Itera el arreglo de Path ejecutando el body por item, hasta Concurrency items a la vez
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runForEach(flow *Instance, ctx et.Json) (et.Json, error) {
+	if len(s.Body) == 0 {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_FOREACH_EMPTY, s.Name)
+	}
+
+	val, ok := getPath(ctx, s.Path)
+	if !ok {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_FOREACH_PATH, s.Path, s.Name)
+	}
+
+	items, ok := toArray(val)
+	if !ok {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_FOREACH_PATH, s.Path, s.Name)
+	}
+
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	n := len(items)
+	results := make([]et.Json, n)
+	errs := make([]error, n)
+	executed := make([]bool, n)
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, item := range items {
+		sem <- struct{}{}
+		if s.StopOnError && failed.Load() {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		executed[i] = true
+		go func(i int, item interface{}) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i], errs[i] = s.runItem(flow, ctx, i, item)
+			if errs[i] != nil {
+				failed.Store(true)
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	attempt := 0
+	if flow.resilence != nil {
+		attempt = flow.resilence.Attempt
+	}
+
+	itemResults := make(map[string]*Result)
+	itemErrors := make([]et.Json, 0)
+	var err error
+	for i := 0; i < n; i++ {
+		if !executed[i] {
+			continue
+		}
+
+		errMessage := ""
+		if errs[i] != nil {
+			errMessage = errs[i].Error()
+			itemErrors = append(itemErrors, et.Json{
+				"index": i,
+				"error": errMessage,
+			})
+			if err == nil {
+				err = errs[i]
+			}
+		}
+
+		itemResults[strconv.Itoa(i)] = &Result{
+			Step:    flow.Current,
+			Ctx:     et.Json{"item": items[i], "index": i},
+			Attempt: attempt,
+			Result:  results[i],
+			Error:   errMessage,
+		}
+	}
+
+	flow.Results[flow.Current] = &Result{
+		Step:     flow.Current,
+		Ctx:      ctx.Clone(),
+		Attempt:  attempt,
+		Branches: itemResults,
+	}
+
+	if s.StopOnError && err != nil {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_FOREACH_FAILED, s.Name, err.Error())
+	}
+
+	return et.Json{
+		"results": results,
+		"errors":  itemErrors,
+	}, nil
+}
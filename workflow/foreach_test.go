@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testItems
+* Body que falla con el item 2 y registra los items procesados
+* @return FnContext, func() []int
+**/
+func testItems() (FnContext, func() []int) {
+	mu := sync.Mutex{}
+	seen := make([]int, 0)
+	fn := func(flow *Instance, ctx et.Json) (et.Json, error) {
+		item := ctx.Int("item")
+		mu.Lock()
+		seen = append(seen, item)
+		mu.Unlock()
+		if item == 2 {
+			return et.Json{}, errors.New("boom")
+		}
+
+		return et.Json{"total": item * 10}, nil
+	}
+
+	return fn, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]int{}, seen...)
+	}
+}
+
+func TestForEachCollectsErrors(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	body, seen := testItems()
+	wf.newFlowFn("foreach_collect", "v1", "ForEach", "", testPass, false, "test").
+		ForEach("Lines", "", "lines", 2, false, false).
+		EachFn("Line", "", body)
+
+	ctx := testVisited(t, "foreach-collect", "foreach_collect", et.Json{"lines": []interface{}{1, 2, 3}})
+	if n := len(seen()); n != 3 {
+		t.Fatalf("every item must run, got %d", n)
+	}
+
+	errs, ok := ctx["errors"].([]interface{})
+	if !ok || len(errs) != 1 {
+		t.Fatalf("the failed item must be collected, got %v", ctx["errors"])
+	}
+	results, ok := ctx["results"].([]interface{})
+	if !ok || len(results) != 3 {
+		t.Fatalf("expected a result per item, got %v", ctx["results"])
+	}
+}
+
+func TestForEachStopOnError(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	body, seen := testItems()
+	wf.newFlowFn("foreach_stop", "v1", "ForEach", "", testPass, false, "test").
+		ForEach("Lines", "", "lines", 1, true, false).
+		EachFn("Line", "", body)
+
+	_, err := Run("foreach-stop", "foreach_stop", 0, et.Json{}, et.Json{"lines": []interface{}{1, 2, 3}}, "test")
+	if err == nil {
+		t.Fatal("the step must fail with stop_on_error")
+	}
+
+	instance := waitStatus(t, "foreach-stop", FlowStatusFailed)
+	if got := seen(); len(got) != 2 || got[1] != 2 {
+		t.Fatalf("no item must start after the failure, got %v", got)
+	}
+	res := instance.Results[1]
+	if res == nil || res.Branches["1"] == nil || res.Branches["1"].Error == "" || res.Branches["2"] != nil {
+		t.Fatal("the executed items must be recorded")
+	}
+}
@@ -1,10 +1,11 @@
 package workflow
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/cgalvisleon/et/cache"
 	"github.com/cgalvisleon/et/et"
@@ -124,6 +125,120 @@ func Run(instanceId, tag string, startId int, tags et.Json, ctx et.Json, created
 	return workFlows.run(instanceId, tag, startId, tags, ctx, createdBy)
 }
 
+/**
+* RunContext
+* @param c context.Context, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string
+* @return et.Json, error
+**/
+func RunContext(c context.Context, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.runContext(c, instanceId, tag, startId, tags, ctx, createdBy)
+}
+
+/**
+* RunAsync
+* Encola la ejecucion en el pool de workers y retorna el id de la instancia de inmediato
+* @param instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string
+* @return (string, error)
+**/
+func RunAsync(instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string) (string, error) {
+	if err := Load(); err != nil {
+		return "", err
+	}
+
+	return workFlows.pool.submit(context.Background(), instanceId, tag, startId, tags, ctx, createdBy, nil)
+}
+
+/**
+* RunIdempotent
+* Run con llave de idempotencia por tag, repetir la llave retorna el resultado de la primera ejecucion
+* o su estado si sigue en ejecucion, con otro payload retorna IdempotencyError
+* @param key, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string
+* @return (et.Json, error)
+**/
+func RunIdempotent(key, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.runIdempotent(context.Background(), key, instanceId, tag, startId, tags, ctx, createdBy)
+}
+
+/**
+* RunAsyncIdempotent
+* RunAsync con llave de idempotencia por tag, repetir la llave retorna el id de la primera ejecucion
+* @param key, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string
+* @return (string, error)
+**/
+func RunAsyncIdempotent(key, instanceId, tag string, startId int, tags et.Json, ctx et.Json, createdBy string) (string, error) {
+	if err := Load(); err != nil {
+		return "", err
+	}
+
+	return workFlows.runAsyncIdempotent(context.Background(), key, instanceId, tag, startId, tags, ctx, createdBy)
+}
+
+/**
+* Await
+* Espera a que termine una ejecucion asincrona, c limita la espera no la ejecucion
+* @param c context.Context, instanceId string
+* @return (et.Json, error)
+**/
+func Await(c context.Context, instanceId string) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.pool.await(c, instanceId)
+}
+
+/**
+* Poll
+* Estado de una ejecucion asincrona: queued, running o finished
+* @param instanceId string
+* @return (et.Json, error)
+**/
+func Poll(instanceId string) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.pool.poll(instanceId)
+}
+
+/**
+* SetLimitRequests
+* Maximo de instancias asincronas en ejecucion simultanea, las demas quedan en cola
+* @param limit int
+* @return error
+**/
+func SetLimitRequests(limit int) error {
+	if err := Load(); err != nil {
+		return err
+	}
+
+	workFlows.pool.setLimit(limit)
+	return nil
+}
+
+/**
+* SetLease
+* Lease que asegura un solo worker por instancia, NewCacheLease para varios workers, nil vuelve al local
+* @param lease Lease, ttl time.Duration
+* @return error
+**/
+func SetLease(lease Lease, ttl time.Duration) error {
+	if err := Load(); err != nil {
+		return err
+	}
+
+	workFlows.setLease(lease, ttl)
+	return nil
+}
+
 /**
 * Continue
 * @param instanceId string, tags et.Json, ctx et.Json, createdBy string
@@ -134,12 +249,46 @@ func Continue(instanceId string, tags et.Json, ctx et.Json, createdBy string) (e
 		return et.Json{}, err
 	}
 
-	instance, exists := workFlows.loadInstance(instanceId)
-	if !exists {
-		return et.Json{}, fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
+	return workFlows.continueContext(context.Background(), instanceId, tags, ctx, createdBy)
+}
+
+/**
+* Signal
+* @param instanceId, name string, payload et.Json
+* @return et.Json, error
+**/
+func Signal(instanceId, name string, payload et.Json) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.signal(instanceId, name, payload)
+}
+
+/**
+* ContinueContext
+* @param c context.Context, instanceId string, tags et.Json, ctx et.Json, createdBy string
+* @return et.Json, error
+**/
+func ContinueContext(c context.Context, instanceId string, tags et.Json, ctx et.Json, createdBy string) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.continueContext(c, instanceId, tags, ctx, createdBy)
+}
+
+/**
+* Cancel
+* @param instanceId string
+* @return error
+**/
+func Cancel(instanceId string) error {
+	if err := Load(); err != nil {
+		return err
 	}
 
-	return workFlows.run(instanceId, instance.Tag, instance.Current, tags, ctx, createdBy)
+	return workFlows.cancel(instanceId)
 }
 
 /**
@@ -168,6 +317,21 @@ func Rollback(instanceId string) (et.Json, error) {
 	return workFlows.rollback(instanceId)
 }
 
+/**
+* Replay
+* Ejecuta de nuevo la instancia desde fromStep con el ctx registrado para ese step,
+* con opts.Clone la ejecucion se hace en una nueva instancia
+* @param instanceId string, fromStep int, opts ReplayOptions
+* @return (et.Json, error)
+**/
+func Replay(instanceId string, fromStep int, opts ReplayOptions) (et.Json, error) {
+	if err := Load(); err != nil {
+		return et.Json{}, err
+	}
+
+	return workFlows.replay(instanceId, fromStep, opts)
+}
+
 /**
 * Stop
 * @param instanceId string
@@ -199,6 +363,20 @@ func GetInstance(instanceId string) (*Instance, error) {
 	return result, nil
 }
 
+/**
+* GetInstanceTree
+* @param instanceId string
+* @return (et.Json, error)
+**/
+func GetInstanceTree(instanceId string) (et.Json, error) {
+	instance, err := GetInstance(instanceId)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	return instance.Tree(), nil
+}
+
 /**
 * DeleteInstance
 * @param instanceId string
@@ -212,6 +390,47 @@ func DeleteInstance(instanceId string) error {
 	return workFlows.delete(instanceId)
 }
 
+/**
+* Restore
+* Carga las instancias pendientes o en espera del store, reprograma sus timers y continua las
+* compensaciones en curso, se llama al iniciar despues de SetStore
+* @return (int, error)
+**/
+func Restore() (int, error) {
+	if err := Load(); err != nil {
+		return 0, err
+	}
+
+	return workFlows.restore()
+}
+
+/**
+* History
+* Transiciones de la instancia en orden: creada, steps, gotos, compensaciones, reset, stop y continue
+* @param instanceId string
+* @return ([]*HistoryEntry, error)
+**/
+func History(instanceId string) ([]*HistoryEntry, error) {
+	if err := Load(); err != nil {
+		return nil, err
+	}
+
+	return workFlows.history(instanceId)
+}
+
+/**
+* ListInstances
+* @param query Query
+* @return ([]*Instance, error)
+**/
+func ListInstances(query Query) ([]*Instance, error) {
+	if err := Load(); err != nil {
+		return nil, err
+	}
+
+	return getStore().ListInstances(query)
+}
+
 /**
 * FlowByTag
 * @param tag string
@@ -222,11 +441,12 @@ func FlowByTag(tag string) (*Flow, error) {
 		return nil, err
 	}
 
-	if getFlow == nil {
-		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	result, err := getStore().GetFlow(tag)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := getFlow(tag)
+	err = result.check()
 	if err != nil {
 		return nil, err
 	}
@@ -245,8 +465,12 @@ func FlowByDefinition(bt []byte) (*Flow, error) {
 		return nil, err
 	}
 
-	var result *Flow
-	err := json.Unmarshal(bt, &result)
+	result, err := parseSpec(bt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = result.check()
 	if err != nil {
 		return nil, err
 	}
@@ -278,44 +502,63 @@ func FlowByParams(params et.Json) (*Flow, error) {
 		return nil, fmt.Errorf(msg.MSG_ATRIB_REQUIRED, "version")
 	}
 
-	description := params.Str("description")
-	definition := params.Str("definition")
-	stop := params.Bool("stop")
-	createdBy := params.Str("createdBy")
-	steps := params.ArrayJson("eteps")
-	result := workFlows.newFlowDefinition(tag, version, name, description, definition, stop, createdBy)
-	for i, step := range steps {
-		name := step.Str("name")
-		description := step.Str("description")
-		if !utility.ValidStr(name, 0, []string{""}) {
-			return nil, fmt.Errorf(MSG_ATTRIBUTE_REQUIRED_STEP, "name", i)
-		}
-		if !utility.ValidStr(description, 0, []string{""}) {
-			return nil, fmt.Errorf(MSG_ATTRIBUTE_REQUIRED_STEP, "description", i)
-		}
-
-		definition := step.Str("definition")
-		stop := step.Bool("stop")
-		result.Step(name, description, definition, stop)
-	}
-
 	models := params.ArrayJson("models")
 	for _, model := range models {
-		dataBase := model.Str("database")
-		name := model.Str("name")
-		if !utility.ValidStr(dataBase, 0, []string{""}) {
+		if !utility.ValidStr(model.Str("database"), 0, []string{""}) {
 			return nil, fmt.Errorf(msg.MSG_ATRIB_REQUIRED, "dataBase")
 		}
-		if !utility.ValidStr(name, 0, []string{""}) {
+		if !utility.ValidStr(model.Str("name"), 0, []string{""}) {
 			return nil, fmt.Errorf(msg.MSG_ATRIB_REQUIRED, "name")
 		}
+	}
 
-		result.AddModel(dataBase, name)
+	if params.Str("spec") == "" {
+		var err error
+		params, err = legacyParams(params)
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	bt, err := params.ToByte()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseSpec(bt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = result.check()
+	if err != nil {
+		return nil, err
+	}
+
+	workFlows.add(result)
+	result.Save()
+	logs.Logf(packageName, MSG_FLOW_CREATED, result.Tag, result.Version, result.Name)
+
 	return result, nil
 }
 
+/**
+* httpFlowError
+* Los problemas de validacion responden 400 con el detalle de cada problema
+* @params w http.ResponseWriter, r *http.Request, err error
+**/
+func httpFlowError(w http.ResponseWriter, r *http.Request, err error) {
+	if problems, ok := err.(Problems); ok {
+		response.JSON(w, r, http.StatusBadRequest, et.Json{
+			"message":  err.Error(),
+			"problems": problems,
+		})
+		return
+	}
+
+	response.HTTPError(w, r, http.StatusInternalServerError, err.Error())
+}
+
 /**
 * HttpLoadByTag
 * @params w http.ResponseWriter, r *http.Request
@@ -324,7 +567,7 @@ func HttpLoadByTag(w http.ResponseWriter, r *http.Request) {
 	tag := chi.URLParam(r, "tag")
 	result, err := FlowByTag(tag)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusInternalServerError, err.Error())
+		httpFlowError(w, r, err)
 		return
 	}
 
@@ -345,7 +588,7 @@ func HttpLoadByDefinition(w http.ResponseWriter, r *http.Request) {
 
 	result, err := FlowByDefinition(definition)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusInternalServerError, err.Error())
+		httpFlowError(w, r, err)
 		return
 	}
 
@@ -360,7 +603,7 @@ func HttpLoadByParams(w http.ResponseWriter, r *http.Request) {
 	body, _ := response.GetBody(r)
 	result, err := FlowByParams(body)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusInternalServerError, err.Error())
+		httpFlowError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"github.com/cgalvisleon/et/utility"
+)
+
+type TpHistory string
+
+const (
+	HistoryCreated       TpHistory = "created"
+	HistoryStatus        TpHistory = "status"
+	HistoryStepStarted   TpHistory = "step_started"
+	HistoryStepCompleted TpHistory = "step_completed"
+	HistoryStepFailed    TpHistory = "step_failed"
+	HistoryStepRetry     TpHistory = "step_retry"
+	HistoryGoto          TpHistory = "goto"
+	HistoryRollback      TpHistory = "rollback"
+	HistoryReset         TpHistory = "reset"
+	HistoryStop          TpHistory = "stop"
+	HistoryContinue      TpHistory = "continue"
+	HistoryReplay        TpHistory = "replay"
+)
+
+/**
+* addHistory
+* Las transiciones se guardan en el store solo despues de guardar la instancia,
+* con un conflicto de revision se descartan
+* @param tp TpHistory, message string
+**/
+func (s *Instance) addHistory(tp TpHistory, message string) {
+	s.history = append(s.history, &HistoryEntry{
+		InstanceId: s.Id,
+		Type:       tp,
+		Step:       s.Current,
+		Status:     s.Status,
+		Actor:      s.UpdatedBy,
+		Message:    message,
+		CreatedAt:  utility.NowTime(),
+	})
+}
+
+/**
+* history
+* @param instanceId string
+* @return []*HistoryEntry, error
+**/
+func (s *WorkFlows) history(instanceId string) ([]*HistoryEntry, error) {
+	return getStore().GetHistory(instanceId)
+}
@@ -0,0 +1,40 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestHistoryWithHooks(t *testing.T) {
+	testWorkFlows(t, nil)
+	testHooks(t)
+
+	NewFn("history", "v1", "History", "", testStep("first", true), false, "test").
+		StepFn("Second", "", testStep("second", true), false)
+	_, err := Run("history-1", "history", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	result, err := History("history-1")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(result) == 0 || result[0].Type != HistoryCreated {
+		t.Fatalf("history must start with created, got %d entries", len(result))
+	}
+
+	completed := 0
+	for _, entry := range result {
+		if entry.Type == HistoryStepCompleted {
+			completed++
+		}
+	}
+	if completed != 2 {
+		t.Fatalf("expected 2 completed steps, got %d", completed)
+	}
+	if last := result[len(result)-1]; last.Status != FlowStatusDone {
+		t.Fatalf("history must end with done, got %s", last.Status)
+	}
+}
@@ -0,0 +1,251 @@
+package workflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cgalvisleon/et/cache"
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/reg"
+)
+
+const (
+	idempotencyKey = "workflow:idempotency"
+	idempotencyTTL = 24 * time.Hour
+)
+
+type idempotency struct {
+	Tag        string  `json:"tag"`
+	Key        string  `json:"key"`
+	Hash       string  `json:"hash"`
+	InstanceId string  `json:"instance_id"`
+	Done       bool    `json:"done"`
+	Result     et.Json `json:"result"`
+	Error      et.Json `json:"error"`
+}
+
+/**
+* cacheKey
+* @return string
+**/
+func (s *idempotency) cacheKey() string {
+	return fmt.Sprintf("%s:%s:%s", idempotencyKey, s.Tag, s.Key)
+}
+
+/**
+* save
+* @return error
+**/
+func (s *idempotency) save() error {
+	bt, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cache.Set(s.cacheKey(), string(bt), idempotencyTTL)
+	return nil
+}
+
+/**
+* complete
+* Guarda el resultado de la primera ejecucion
+* @param result et.Json, err error
+**/
+func (s *idempotency) complete(result et.Json, err error) {
+	s.Done = true
+	s.Result = result
+	s.Error = encodeError(err)
+
+	if err := s.save(); err != nil {
+		logs.Error(err)
+	}
+}
+
+/**
+* finish
+* Sin started la instancia no se creo (flujo no encontrado, id ya en ejecucion) y la llave se libera
+* para que un reintento pueda ejecutar, si no se guarda el resultado
+* @param result et.Json, err error, started bool
+**/
+func (s *idempotency) finish(result et.Json, err error, started bool) {
+	if !started {
+		s.release()
+		return
+	}
+
+	s.complete(result, err)
+}
+
+/**
+* release
+* Libera la llave cuando la ejecucion no se pudo iniciar
+**/
+func (s *idempotency) release() {
+	for _, key := range []string{s.cacheKey(), s.cacheKey() + ":claim"} {
+		if _, err := cache.Delete(key); err != nil {
+			logs.Error(err)
+		}
+	}
+}
+
+/**
+* payloadHash
+* @param step int, tags, ctx et.Json
+* @return string, error
+**/
+func payloadHash(step int, tags, ctx et.Json) (string, error) {
+	bt, err := json.Marshal(et.Json{
+		"step": step,
+		"tags": tags,
+		"ctx":  ctx,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(bt)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/**
+* claim
+* La primera llamada con la llave registra la ejecucion, las siguientes reciben el registro existente
+* @param tag, key, instanceId string, step int, tags, ctx et.Json
+* @return *idempotency, bool, error
+**/
+func (s *WorkFlows) claim(tag, key, instanceId string, step int, tags, ctx et.Json) (*idempotency, bool, error) {
+	hash, err := payloadHash(step, tags, ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := &idempotency{
+		Tag:        tag,
+		Key:        key,
+		Hash:       hash,
+		InstanceId: reg.GetUUID(instanceId),
+	}
+	n := cache.Incr(result.cacheKey()+":claim", idempotencyTTL)
+	if n == 0 {
+		return nil, false, fmt.Errorf(MSG_CACHE_UNAVAILABLE)
+	}
+
+	if n == 1 {
+		return result, false, result.save()
+	}
+
+	bt, err := cache.Get(result.cacheKey(), "")
+	if err != nil {
+		return nil, false, err
+	}
+
+	if bt == "" {
+		return nil, false, fmt.Errorf(MSG_IDEMPOTENCY_IN_PROGRESS, key, tag)
+	}
+
+	var current *idempotency
+	err = json.Unmarshal([]byte(bt), &current)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if current.Hash != hash {
+		return nil, true, &IdempotencyError{Tag: tag, Key: key}
+	}
+
+	return current, true, nil
+}
+
+/**
+* stored
+* Resultado de la primera ejecucion o su estado si aun no termina
+* @param record *idempotency
+* @return et.Json, error
+**/
+func (s *WorkFlows) stored(record *idempotency) (et.Json, error) {
+	if !record.Done {
+		return s.pool.poll(record.InstanceId)
+	}
+
+	return record.Result, decodeError(record.Error)
+}
+
+/**
+* runIdempotent
+* @param c context.Context, key, instanceId, tag string, step int, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) runIdempotent(c context.Context, key, instanceId, tag string, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
+	if key == "" {
+		return s.runContext(c, instanceId, tag, step, tags, ctx, runBy)
+	}
+
+	record, exists, err := s.claim(tag, key, instanceId, step, tags, ctx)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	if exists {
+		return s.stored(record)
+	}
+
+	return s.runTracked(c, record, tag, step, tags, ctx, runBy)
+}
+
+/**
+* runTracked
+* La ejecucion sincrona se registra en el pool para que las llamadas repetidas vean su estado
+* @param c context.Context, record *idempotency, tag string, step int, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) runTracked(c context.Context, record *idempotency, tag string, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
+	item, err := s.pool.track(record.InstanceId, tag)
+	if err != nil {
+		record.finish(et.Json{}, err, false)
+		return et.Json{}, err
+	}
+
+	result := et.Json{}
+	instance, err := s.getOrCreateInstance(record.InstanceId, tag, step, tags, runBy)
+	started := err == nil
+	if started {
+		result, err = s.runInstance(c, instance, step, tags, ctx, runBy)
+	}
+	record.finish(result, err, started)
+	s.pool.finish(item, result, err)
+
+	return result, err
+}
+
+/**
+* runAsyncIdempotent
+* Una llamada repetida retorna el id de la instancia de la primera ejecucion
+* @param c context.Context, key, instanceId, tag string, step int, tags, ctx et.Json, runBy string
+* @return string, error
+**/
+func (s *WorkFlows) runAsyncIdempotent(c context.Context, key, instanceId, tag string, step int, tags, ctx et.Json, runBy string) (string, error) {
+	if key == "" {
+		return s.pool.submit(c, instanceId, tag, step, tags, ctx, runBy, nil)
+	}
+
+	record, exists, err := s.claim(tag, key, instanceId, step, tags, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		return record.InstanceId, nil
+	}
+
+	result, err := s.pool.submit(c, record.InstanceId, tag, step, tags, ctx, runBy, record.finish)
+	if err != nil {
+		record.release()
+	}
+
+	return result, err
+}
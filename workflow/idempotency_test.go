@@ -0,0 +1,189 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestPayloadHash(t *testing.T) {
+	a, err := payloadHash(0, et.Json{"env": "prod"}, et.Json{"id": 1, "name": "order"})
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+
+	b, err := payloadHash(0, et.Json{"env": "prod"}, et.Json{"name": "order", "id": 1})
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if a != b {
+		t.Fatal("same payload must have the same hash")
+	}
+
+	for _, tc := range []struct {
+		step      int
+		tags, ctx et.Json
+	}{
+		{1, et.Json{"env": "prod"}, et.Json{"id": 1, "name": "order"}},
+		{0, et.Json{"env": "dev"}, et.Json{"id": 1, "name": "order"}},
+		{0, et.Json{"env": "prod"}, et.Json{"id": 2, "name": "order"}},
+	} {
+		c, err := payloadHash(tc.step, tc.tags, tc.ctx)
+		if err != nil {
+			t.Fatalf("hash: %v", err)
+		}
+		if c == a {
+			t.Fatalf("different payload must change the hash: %v %v %v", tc.step, tc.tags, tc.ctx)
+		}
+	}
+}
+
+/**
+* roundTrip
+* Codifica el error y lo decodifica como lo hace un registro guardado en cache
+* @param t *testing.T, err error
+* @return error
+**/
+func roundTrip(t *testing.T, err error) error {
+	t.Helper()
+
+	record := &idempotency{Error: encodeError(err)}
+	bt, e := json.Marshal(record)
+	if e != nil {
+		t.Fatalf("marshal: %v", e)
+	}
+
+	var result *idempotency
+	if e := json.Unmarshal(bt, &result); e != nil {
+		t.Fatalf("unmarshal: %v", e)
+	}
+
+	return decodeError(result.Error)
+}
+
+func TestIdempotencyErrorRoundTrip(t *testing.T) {
+	if err := roundTrip(t, nil); err != nil {
+		t.Fatalf("nil error must stay nil, got %v", err)
+	}
+
+	err := roundTrip(t, NewError("payment_declined", "card %s declined", "visa"))
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		t.Fatalf("expected StepError, got %T", err)
+	}
+	if stepErr.Code != "payment_declined" || stepErr.Message != "card visa declined" {
+		t.Fatalf("unexpected StepError %+v", stepErr)
+	}
+	if errorCode(err) != "payment_declined" {
+		t.Fatalf("error code lost, got %q", errorCode(err))
+	}
+
+	err = roundTrip(t, &ConflictError{InstanceId: "abc", Expected: 2, Current: 3})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError, got %T", err)
+	}
+	if conflict.InstanceId != "abc" || conflict.Expected != 2 || conflict.Current != 3 {
+		t.Fatalf("unexpected ConflictError %+v", conflict)
+	}
+
+	err = roundTrip(t, &IdempotencyError{Tag: "orders", Key: "k1"})
+	var idempotencyErr *IdempotencyError
+	if !errors.As(err, &idempotencyErr) {
+		t.Fatalf("expected IdempotencyError, got %T", err)
+	}
+	if idempotencyErr.Tag != "orders" || idempotencyErr.Key != "k1" {
+		t.Fatalf("unexpected IdempotencyError %+v", idempotencyErr)
+	}
+
+	err = roundTrip(t, errors.New("boom"))
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if errors.As(err, &stepErr) {
+		t.Fatal("plain error must not become a StepError")
+	}
+}
+
+func TestRunTrackedReportsRunning(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	block := func(flow *Instance, ctx et.Json) (et.Json, error) {
+		close(started)
+		<-release
+		return et.Json{"ok": true}, nil
+	}
+	wf.newFlowFn("tracked", "v1", "Tracked", "", block, false, "test")
+
+	record := &idempotency{Tag: "tracked", Key: "k1", InstanceId: "tracked-1"}
+	done := make(chan error, 1)
+	go func() {
+		_, err := wf.runTracked(context.Background(), record, "tracked", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	result, err := wf.pool.poll("tracked-1")
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if result["status"] != JobStatusRunning {
+		t.Fatalf("a sync run in progress must be reported as running, got %v", result["status"])
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	result, err = wf.pool.poll("tracked-1")
+	if err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if result["status"] != JobStatusFinished {
+		t.Fatalf("expected finished, got %v", result["status"])
+	}
+	if !record.Done {
+		t.Fatal("the result of a started run must be stored")
+	}
+}
+
+func TestRunTrackedReleasesStartupFailure(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+
+	record := &idempotency{Tag: "missing", Key: "k1", InstanceId: "missing-1"}
+	_, err := wf.runTracked(context.Background(), record, "missing", 0, et.Json{}, et.Json{}, "test")
+	if err == nil {
+		t.Fatal("expected flow not found")
+	}
+	if record.Done {
+		t.Fatal("a run that never started must not be stored as the result of the key")
+	}
+
+	result, err := wf.pool.poll("missing-1")
+	if err == nil && result["status"] != JobStatusFinished {
+		t.Fatalf("expected finished, got %v", result["status"])
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status FlowStatus
+		expect TpJobStatus
+	}{
+		{FlowStatusPending, JobStatusRunning},
+		{FlowStatusRunning, JobStatusRunning},
+		{FlowStatusRollingBack, JobStatusRunning},
+		{FlowStatusWaiting, JobStatusFinished},
+		{FlowStatusDone, JobStatusFinished},
+		{FlowStatusFailed, JobStatusFinished},
+	} {
+		if got := jobStatus(tc.status); got != tc.expect {
+			t.Fatalf("%s: expected %s, got %s", tc.status, tc.expect, got)
+		}
+	}
+}
@@ -1,8 +1,13 @@
 package workflow
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/cgalvisleon/et/et"
@@ -11,16 +16,19 @@ import (
 	"github.com/cgalvisleon/et/resilience"
 	"github.com/cgalvisleon/et/utility"
 	"github.com/cgalvisleon/workflow/vm"
+	"github.com/dop251/goja"
 )
 
 type SetFn func(*Instance) error
 type GetFn func(string) (*Instance, error)
 type DeleteFn func(string) error
+type ListFn func(Query) ([]*Instance, error)
 
 var (
 	getFn    GetFn
 	setFn    SetFn
 	deleteFn DeleteFn
+	listFn   ListFn
 )
 
 /**
@@ -34,10 +42,14 @@ func OnGet(f GetFn) {
 	}
 
 	getFn = f
+	useHooks()
 }
 
 /**
 * OnSet
+* La funcion recibe la instancia con Revision ya incrementado, para evitar que dos workers se
+* pisen debe guardar solo si la revision almacenada es instance.Revision-1 y si no retornar un
+* *ConflictError, la ejecucion se detiene con ese error
 * @param f SetFn
 * @return void
 **/
@@ -47,6 +59,7 @@ func OnSet(f SetFn) {
 	}
 
 	setFn = f
+	useHooks()
 }
 
 /**
@@ -60,41 +73,85 @@ func OnDelete(f DeleteFn) {
 	}
 
 	deleteFn = f
+	useHooks()
+}
+
+/**
+* OnList
+* Restore la usa para cargar las instancias pendientes y en espera, sin ella los timers
+* de Sleep y WaitSignal no se reconstruyen al reiniciar
+* @param f ListFn
+* @return void
+**/
+func OnList(f ListFn) {
+	if f == nil {
+		return
+	}
+
+	listFn = f
+	useHooks()
 }
 
 type FlowStatus string
 
 const (
-	FlowStatusPending FlowStatus = "pending"
-	FlowStatusRunning FlowStatus = "running"
-	FlowStatusDone    FlowStatus = "done"
-	FlowStatusFailed  FlowStatus = "failed"
+	FlowStatusPending            FlowStatus = "pending"
+	FlowStatusRunning            FlowStatus = "running"
+	FlowStatusDone               FlowStatus = "done"
+	FlowStatusFailed             FlowStatus = "failed"
+	FlowStatusWaiting            FlowStatus = "waiting"
+	FlowStatusCancelled          FlowStatus = "cancelled"
+	FlowStatusRollingBack        FlowStatus = "rolling_back"
+	FlowStatusRolledBack         FlowStatus = "rolled_back"
+	FlowStatusCompensationFailed FlowStatus = "compensation_failed"
 )
 
 type Instance struct {
 	*Flow
-	workFlows  *WorkFlows           `json:"-"`
-	CreatedAt  time.Time            `json:"created_at"`
-	UpdatedAt  time.Time            `json:"updated_at"`
-	Tag        string               `json:"tag"`
-	Id         string               `json:"id"`
-	CreatedBy  string               `json:"created_by"`
-	UpdatedBy  string               `json:"updated_by"`
-	Status     FlowStatus           `json:"status"`
-	DoneAt     time.Time            `json:"done_at"`
-	Current    int                  `json:"current"`
-	Ctx        et.Json              `json:"ctx"`
-	Ctxs       map[int]et.Json      `json:"ctxs"`
-	PinnedData et.Json              `json:"pinned_data"`
-	Results    map[int]*Result      `json:"results"`
-	Tags       et.Json              `json:"tags"`
-	Rollbacks  map[int]*Result      `json:"rollbacks"`
-	WorkerHost string               `json:"worker_host"`
-	vm         *vm.Vm               `json:"-"`
-	done       bool                 `json:"-"`
-	goTo       int                  `json:"-"`
-	err        error                `json:"-"`
-	resilence  *resilience.Instance `json:"-"`
+	workFlows      *WorkFlows           `json:"-"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Tag            string               `json:"tag"`
+	Id             string               `json:"id"`
+	CreatedBy      string               `json:"created_by"`
+	UpdatedBy      string               `json:"updated_by"`
+	Status         FlowStatus           `json:"status"`
+	Revision       int64                `json:"revision"`
+	DoneAt         time.Time            `json:"done_at"`
+	Current        int                  `json:"current"`
+	Ctx            et.Json              `json:"ctx"`
+	Ctxs           map[int]et.Json      `json:"ctxs"`
+	PinnedData     et.Json              `json:"pinned_data"`
+	Results        map[int]*Result      `json:"results"`
+	Tags           et.Json              `json:"tags"`
+	Rollbacks      map[int]*Result      `json:"rollbacks"`
+	Completed      []int                `json:"completed"`
+	WorkerHost     string               `json:"worker_host"`
+	ParentId       string               `json:"parent_id"`
+	Children       []string             `json:"children"`
+	WaitingSignal  string               `json:"waiting_signal"`
+	SignalDeadline time.Time            `json:"signal_deadline"`
+	Signals        map[string]et.Json   `json:"signals"`
+	WakeAt         time.Time            `json:"wake_at"`
+	ReplayOf       string               `json:"replay_of"`
+	Executions     []*Execution         `json:"executions"`
+	History        []*HistoryEntry      `json:"history,omitempty"`
+	vm             *vm.Vm               `json:"-"`
+	done           bool                 `json:"-"`
+	goTo           int                  `json:"-"`
+	suspended      bool                 `json:"-"`
+	context        context.Context      `json:"-"`
+	cancel         context.CancelFunc   `json:"-"`
+	aborted        error                `json:"-"`
+	err            error                `json:"-"`
+	resilence      *resilience.Instance `json:"-"`
+	mu             sync.Mutex           `json:"-"`
+	ctxMu          sync.Mutex           `json:"-"`
+	inbox          map[string]et.Json   `json:"-"`
+	inboxMu        sync.Mutex           `json:"-"`
+	history        []*HistoryEntry      `json:"-"`
+	results        []*Result            `json:"-"`
+	forked         bool                 `json:"-"`
 }
 
 /**
@@ -135,19 +192,38 @@ func (s *Instance) ToJson() et.Json {
 
 /**
 * Save
+* Los resultados y el historial pendientes se guardan con la instancia. Una instancia abortada
+* no guarda y la copia de un step con timeout guarda al terminar el step
 * @return error
 **/
 func (s *Instance) Save() error {
-	if setFn != nil {
-		err := setFn(s)
-		if err != nil {
-			err = fmt.Errorf("setFn: error on save instanceId: %s, error: %v", s.Id, err)
-			event.Publish(EVENT_ERROR, et.Json{
-				"message": err.Error(),
-			})
-			return err
+	if err := s.abortErr(); err != nil {
+		return err
+	}
+
+	if s.forked {
+		return nil
+	}
+
+	revision := s.Revision
+	s.Revision++
+	err := getStore().SetInstance(s, revision, &Changes{Results: s.results, History: s.history})
+	if err != nil {
+		s.Revision = revision
+		err = fmt.Errorf("setFn: error on save instanceId: %s, error: %w", s.Id, err)
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			s.results = nil
+			s.history = nil
+			s.abort(err)
 		}
+		event.Publish(EVENT_ERROR, et.Json{
+			"message": err.Error(),
+		})
+		return err
 	}
+	s.results = nil
+	s.history = nil
 	event.Publish(EVENT_WORKFLOW_SET, s.ToJson())
 	return nil
 }
@@ -168,6 +244,7 @@ func (s *Instance) SetStatus(status FlowStatus) error {
 
 	s.Status = status
 	s.UpdatedAt = utility.NowTime()
+	s.addHistory(HistoryStatus, string(status))
 
 	if s.Status == FlowStatusDone {
 		s.DoneAt = s.UpdatedAt
@@ -246,7 +323,12 @@ func (s *Instance) SetResult(result et.Json, err error) (et.Json, error) {
 		Result:  result,
 		Error:   errMessage,
 	}
+	if prev := s.Results[s.Current]; prev != nil {
+		res.Branches = prev.Branches
+		res.Attempts = prev.Attempts
+	}
 	s.Results[s.Current] = res
+	s.results = append(s.results, res)
 
 	return result, err
 }
@@ -299,6 +381,41 @@ func (s *Instance) setStop(result et.Json, err error) (et.Json, error) {
 	return result, err
 }
 
+/**
+* setWaiting
+* La instancia queda en espera sin avanzar de step, al reanudar se vuelve a ejecutar el step actual
+* @param result et.Json, err error
+* @return et.Json, error
+**/
+func (s *Instance) setWaiting(result et.Json, err error) (et.Json, error) {
+	s.suspended = false
+	s.SetResult(result, err)
+	s.SetStatus(FlowStatusWaiting)
+	logs.Logf(packageName, MSG_INSTANCE_WAITING, s.Id, s.Tag, s.Current)
+
+	return result, err
+}
+
+/**
+* suspend
+* Usado por los steps para dejar la instancia en espera
+**/
+func (s *Instance) suspend() {
+	s.suspended = true
+}
+
+/**
+* setCancelled
+* @param result et.Json, err error
+* @return et.Json, error
+**/
+func (s *Instance) setCancelled(result et.Json, err error) (et.Json, error) {
+	s.SetResult(result, err)
+	s.SetStatus(FlowStatusCancelled)
+
+	return result, err
+}
+
 /**
 * setNext
 * @return error
@@ -319,6 +436,7 @@ func (s *Instance) setNext(result et.Json, err error) (et.Json, error) {
 func (s *Instance) setGoto(step int, message string, result et.Json, err error) (et.Json, error) {
 	s.SetCtx(result)
 	s.SetResult(result, err)
+	s.addHistory(HistoryGoto, fmt.Sprintf(MSG_INSTANCE_GOTO_HISTORY, step, message))
 	s.SetStep(step)
 	s.goTo = -1
 	s.SetStatus(s.Status)
@@ -327,12 +445,327 @@ func (s *Instance) setGoto(step int, message string, result et.Json, err error)
 	return result, err
 }
 
+/**
+* Context
+* Contexto de la ejecucion actual, los steps Go lo deben consultar para cancelar su trabajo
+* @return context.Context
+**/
+func (s *Instance) Context() context.Context {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+
+	if s.context == nil {
+		return context.Background()
+	}
+
+	return s.context
+}
+
+/**
+* setContext
+* @param c context.Context
+* @return func()
+**/
+func (s *Instance) setContext(c context.Context) func() {
+	if c == nil {
+		c = context.Background()
+	}
+
+	s.ctxMu.Lock()
+	s.context, s.cancel = context.WithCancel(c)
+	cancel := s.cancel
+	s.ctxMu.Unlock()
+
+	return func() {
+		cancel()
+		s.ctxMu.Lock()
+		s.context = nil
+		s.cancel = nil
+		s.ctxMu.Unlock()
+	}
+}
+
+/**
+* withContext
+* Reemplaza el contexto durante un step, las goroutines del step deben terminar antes de restaurarlo
+* @param c context.Context
+* @return func()
+**/
+func (s *Instance) withContext(c context.Context) func() {
+	s.ctxMu.Lock()
+	parent := s.context
+	s.context = c
+	s.ctxMu.Unlock()
+
+	return func() {
+		s.ctxMu.Lock()
+		s.context = parent
+		s.ctxMu.Unlock()
+	}
+}
+
+/**
+* fork
+* Copia de la instancia para un step con timeout, la funcion trabaja sobre la copia y sus
+* cambios pasan a la instancia con join solo si termina a tiempo
+* @param c context.Context
+* @return *Instance
+**/
+func (s *Instance) fork(c context.Context) *Instance {
+	result := &Instance{
+		Flow:           s.Flow,
+		workFlows:      s.workFlows,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+		Tag:            s.Tag,
+		Id:             s.Id,
+		CreatedBy:      s.CreatedBy,
+		UpdatedBy:      s.UpdatedBy,
+		Status:         s.Status,
+		Revision:       s.Revision,
+		DoneAt:         s.DoneAt,
+		Current:        s.Current,
+		Ctx:            s.Ctx.Clone(),
+		Ctxs:           make(map[int]et.Json, len(s.Ctxs)),
+		PinnedData:     s.PinnedData.Clone(),
+		Results:        maps.Clone(s.Results),
+		Tags:           s.Tags.Clone(),
+		Rollbacks:      maps.Clone(s.Rollbacks),
+		Completed:      slices.Clone(s.Completed),
+		WorkerHost:     s.WorkerHost,
+		ParentId:       s.ParentId,
+		Children:       slices.Clone(s.Children),
+		WaitingSignal:  s.WaitingSignal,
+		SignalDeadline: s.SignalDeadline,
+		Signals:        maps.Clone(s.Signals),
+		WakeAt:         s.WakeAt,
+		ReplayOf:       s.ReplayOf,
+		Executions:     slices.Clone(s.Executions),
+		vm:             s.vm,
+		done:           s.done,
+		goTo:           s.goTo,
+		suspended:      s.suspended,
+		context:        c,
+		resilence:      s.resilence,
+		forked:         true,
+	}
+	for idx, ctx := range s.Ctxs {
+		result.Ctxs[idx] = ctx.Clone()
+	}
+
+	return result
+}
+
+/**
+* join
+* Pasa a la instancia los cambios de la copia de un step que termino a tiempo
+* @param fork *Instance
+**/
+func (s *Instance) join(fork *Instance) {
+	s.UpdatedAt = fork.UpdatedAt
+	s.UpdatedBy = fork.UpdatedBy
+	s.Status = fork.Status
+	s.DoneAt = fork.DoneAt
+	s.Current = fork.Current
+	s.Ctx = fork.Ctx
+	s.Ctxs = fork.Ctxs
+	s.PinnedData = fork.PinnedData
+	s.Results = fork.Results
+	s.Tags = fork.Tags
+	s.done = fork.done
+	s.goTo = fork.goTo
+	s.suspended = fork.suspended
+	s.err = fork.err
+	s.history = append(s.history, fork.history...)
+	s.results = append(s.results, fork.results...)
+}
+
+/**
+* abort
+* Detiene la ejecucion actual, el run retorna err en el siguiente punto de control
+* @param err error
+**/
+func (s *Instance) abort(err error) {
+	s.ctxMu.Lock()
+	if s.aborted == nil {
+		s.aborted = err
+	}
+	cancel := s.cancel
+	s.ctxMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+/**
+* interrupt
+* Cancela el contexto si la instancia esta en ejecucion
+* @return bool
+**/
+func (s *Instance) interrupt() bool {
+	s.ctxMu.Lock()
+	cancel := s.cancel
+	s.ctxMu.Unlock()
+
+	if cancel == nil {
+		return false
+	}
+
+	cancel()
+	return true
+}
+
+/**
+* abortErr
+* @return error
+**/
+func (s *Instance) abortErr() error {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+
+	return s.aborted
+}
+
+/**
+* interruptOnDone
+* Interrumpe la vm cuando se cancela el contexto de la instancia
+* @param v *vm.Vm
+* @return func()
+**/
+func (s *Instance) interruptOnDone(v *vm.Vm) func() {
+	c := s.Context()
+	if c.Done() == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-c.Done():
+			v.Interrupt(c.Err())
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+/**
+* plain
+* goja expone un mapa con metodos como et.Json sin sus llaves, la definicion recibe
+* copias como mapas simples, tambien las que corren en paralelo
+* @param val interface{}
+* @return interface{}
+**/
+func plain(val interface{}) interface{} {
+	switch v := val.(type) {
+	case et.Json:
+		return plain(map[string]interface{}(v))
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[k] = plain(item)
+		}
+		return result
+	case map[int]et.Json:
+		result := make(map[int]interface{}, len(v))
+		for k, item := range v {
+			result[k] = plain(item)
+		}
+		return result
+	case []et.Json:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = plain(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = plain(item)
+		}
+		return result
+	default:
+		return val
+	}
+}
+
+/**
+* runDefinition
+* La definicion recibe copias de ctx, ctxs y pinnedData
+* @param v *vm.Vm, definition string, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Instance) runDefinition(v *vm.Vm, definition string, ctx et.Json) (et.Json, error) {
+	v.ClearInterrupt()
+	stop := s.interruptOnDone(v)
+	defer stop()
+
+	v.Set("instance", s)
+	v.Set("ctx", plain(ctx))
+	v.Set("ctxs", plain(s.Ctxs))
+	v.Set("pinnedData", plain(s.PinnedData))
+	for k, m := range s.models {
+		v.Set(k, m)
+	}
+
+	if definition == "" {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_DEFINITION_EMPTY)
+	}
+
+	value, err := v.Run(definition)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	if s.isDebug {
+		logs.Debugf("stepDefinition:%s", value.String())
+	}
+
+	result := v.Get("result")
+	if result == goja.Undefined() {
+		return ctx, nil
+	}
+
+	if result == goja.Null() {
+		return ctx, nil
+	}
+
+	if result == nil {
+		return ctx, nil
+	}
+
+	switch r := result.Export().(type) {
+	case map[string]interface{}:
+		return et.Json(r), nil
+	case et.Json:
+		return r, nil
+	default:
+		return et.Json{
+			"result": r,
+		}, nil
+	}
+}
+
 /**
 * run
+* Si la instancia se aborta (ConflictError al guardar) la ejecucion se detiene y retorna ese error
 * @param ctx et.Json, runerBy string
 * @return et.Json, error
 **/
-func (s *Instance) run(ctx et.Json, runerBy string) (et.Json, error) {
+func (s *Instance) run(ctx et.Json, runerBy string) (result et.Json, err error) {
+	s.ctxMu.Lock()
+	s.aborted = nil
+	s.ctxMu.Unlock()
+	defer func() {
+		if aborted := s.abortErr(); aborted != nil {
+			err = aborted
+		}
+	}()
+
 	if s.Status == FlowStatusDone {
 		return s.ToJson(), fmt.Errorf(MSG_INSTANCE_ALREADY_DONE)
 	} else if s.Status == FlowStatusRunning {
@@ -343,18 +776,55 @@ func (s *Instance) run(ctx et.Json, runerBy string) (et.Json, error) {
 		return s.ToJson(), fmt.Errorf(MSG_INSTANCE_ALREADY_DONE)
 	} else if s.done {
 		return s.ToJson(), fmt.Errorf(MSG_INSTANCE_ALREADY_DONE)
+	} else if s.Status == FlowStatusCancelled {
+		return s.ToJson(), fmt.Errorf(MSG_INSTANCE_CANCELLED)
+	} else if s.Status == FlowStatusRollingBack {
+		return s.compensate()
+	} else if s.Status == FlowStatusRolledBack || s.Status == FlowStatusCompensationFailed {
+		return s.ToJson(), fmt.Errorf(MSG_INSTANCE_ROLLED_BACK, s.Status)
 	}
 
 	s.UpdatedBy = runerBy
-	var err error
 	for s.Current < len(s.Steps) {
+		if aborted := s.abortErr(); aborted != nil {
+			return ctx, aborted
+		}
+
+		if cancelErr := s.Context().Err(); cancelErr != nil {
+			return s.setCancelled(ctx, cancelErr)
+		}
+
 		step := s.Steps[s.Current]
+		s.trace(ctx)
 		ctx = s.SetCtx(ctx)
-		ctx, err = step.run(s, ctx)
+		s.addHistory(HistoryStepStarted, step.Name)
+		ctx, err = s.runStep(step, ctx)
+		if aborted := s.abortErr(); aborted != nil {
+			return ctx, aborted
+		}
+
+		if err != nil && s.Context().Err() != nil {
+			return s.setCancelled(ctx, err)
+		}
+
 		if err != nil {
-			return s.rollback(ctx, err)
+			s.addHistory(HistoryStepFailed, err.Error())
+			handler := step.catch(err, len(s.Steps))
+			if handler == nil {
+				return s.rollback(ctx, err)
+			}
+
+			ctx, _ = s.setCatch(step, handler, err)
+			continue
 		}
 
+		if s.suspended {
+			return s.setWaiting(ctx, err)
+		}
+
+		s.setCompleted(s.Current)
+		s.addHistory(HistoryStepCompleted, step.Name)
+
 		if s.done {
 			return s.setDone(ctx, err)
 		}
@@ -379,6 +849,22 @@ func (s *Instance) run(ctx et.Json, runerBy string) (et.Json, error) {
 			} else {
 				s.setGoto(step.NoGoTo, MSG_INSTANCE_EXPRESSION_FALSE, ctx, err)
 			}
+
+			if step.goToByName(ok) {
+				continue
+			}
+		}
+
+		if step.Switch != "" {
+			goTo, key, err := step.evaluateSwitch(ctx, s)
+			if err != nil {
+				return s.rollback(ctx, err)
+			}
+
+			if goTo != -1 {
+				s.setGoto(goTo, fmt.Sprintf(MSG_INSTANCE_SWITCH_CASE, key), ctx, err)
+				continue
+			}
 		}
 
 		if s.Current == len(s.Steps)-1 {
@@ -398,53 +884,23 @@ func (s *Instance) run(ctx et.Json, runerBy string) (et.Json, error) {
 **/
 func (s *Instance) rollback(result et.Json, err error) (et.Json, error) {
 	s.setFailed(result, err)
-	if s.TotalAttempts == 0 {
-		return result, err
-	} else if s.Status == FlowStatusDone {
-		return result, fmt.Errorf(MSG_INSTANCE_ALREADY_DONE)
-	} else if s.Status == FlowStatusPending {
-		return result, fmt.Errorf(MSG_INSTANCE_PENDING)
-	}
-
-	if s.resilence == nil {
-		description := fmt.Sprintf("flow: %s,  %s", s.Name, s.Description)
-		s.resilence = resilience.AddCustom(s.Id, s.Tag, description, s.TotalAttempts, s.TimeAttempts, s.RetentionTime, s.Tags, s.Team, s.Level, s.run, s.Ctx)
-	}
-
-	for i := s.Current - 1; i >= 0; i-- {
-		logs.Logf(packageName, MSG_INSTANCE_ROLLBACK_STEP, i)
-		step := s.Steps[i]
-		if step == nil {
-			continue
-		}
-
-		if step.rollbacks == nil {
-			continue
+	if s.TotalAttempts > 0 && !s.done && s.workFlows != nil {
+		if s.resilence == nil {
+			description := fmt.Sprintf("flow: %s,  %s", s.Name, s.Description)
+			retry := func(ctx et.Json) (et.Json, error) {
+				return s.workFlows.retry(s, ctx)
+			}
+			s.resilence = resilience.AddCustom(s.Id, s.Tag, description, s.TotalAttempts, s.TimeAttempts, s.RetentionTime, s.Tags, s.Team, s.Level, retry, s.Ctx)
 		}
 
-		if s.Ctxs[i] == nil {
-			continue
+		if s.resilence != nil && !s.resilence.IsEnd() {
+			return result, err
 		}
+	}
 
-		ctx := s.Ctxs[i].Clone()
-		result, err = step.rollbacks(s, ctx)
-		if err != nil {
-			attempt := 0
-			if s.resilence != nil {
-				attempt = s.resilence.Attempt
-			}
-			s.Rollbacks[i] = &Result{
-				Step:    i,
-				Ctx:     ctx,
-				Attempt: attempt,
-				Result:  result,
-				Error:   err.Error(),
-			}
-
-			if s.TpConsistency == TpConsistencyStrong {
-				return result, err
-			}
-		}
+	_, compensateErr := s.compensate()
+	if compensateErr != nil {
+		return result, compensateErr
 	}
 
 	return result, err
@@ -456,9 +912,8 @@ func (s *Instance) rollback(result et.Json, err error) (et.Json, error) {
 **/
 func (s *Instance) Stop() error {
 	s.Steps[s.Current].Stop = true
-	s.SetStatus(s.Status)
-
-	return nil
+	s.addHistory(HistoryStop, s.Steps[s.Current].Name)
+	return s.SetStatus(s.Status)
 }
 
 /**
@@ -482,3 +937,17 @@ func (s *Instance) Goto(step int) error {
 
 	return nil
 }
+
+/**
+* GotoByName
+* @param name string
+* @return error
+**/
+func (s *Instance) GotoByName(name string) error {
+	idx := s.IndexOf(name)
+	if idx == -1 {
+		return fmt.Errorf(MSG_STEP_NOT_FOUND, name, s.Tag)
+	}
+
+	return s.Goto(idx)
+}
@@ -0,0 +1,167 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testPass
+* Step que retorna el ctx recibido
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func testPass(flow *Instance, ctx et.Json) (et.Json, error) {
+	return ctx, nil
+}
+
+/**
+* testVisited
+* Corre la instancia y retorna el ctx final, cada step marca su nombre en el ctx
+* @param t *testing.T, instanceId, tag string, ctx et.Json
+* @return et.Json
+**/
+func testVisited(t *testing.T, instanceId, tag string, ctx et.Json) et.Json {
+	t.Helper()
+
+	_, err := Run(instanceId, tag, 0, et.Json{}, ctx, "test")
+	if err != nil {
+		t.Fatalf("run %s: %v", instanceId, err)
+	}
+
+	return waitStatus(t, instanceId, FlowStatusDone).Ctx
+}
+
+func TestIfElseByNameRunsTarget(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("ifelse_name", "v1", "IfElse", "", testPass, false, "test").
+		IfElseByName("total > 10", "Big", "Small").
+		StepFn("Small", "", testStep("small", true), false).
+		StepFn("Big", "", testStep("big", true), false).
+		StepFn("End", "", testStep("end", true), false)
+
+	ctx := testVisited(t, "ifelse-big", "ifelse_name", et.Json{"total": 20})
+	if ctx["small"] != nil || ctx["big"] != true || ctx["end"] != true {
+		t.Fatalf("true branch must run Big and End, ctx %v", ctx)
+	}
+
+	ctx = testVisited(t, "ifelse-small", "ifelse_name", et.Json{"total": 5})
+	if ctx["small"] != true || ctx["big"] != true || ctx["end"] != true {
+		t.Fatalf("false branch must run Small and continue, ctx %v", ctx)
+	}
+}
+
+func TestIfElseByIndexRunsNext(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("ifelse_index", "v1", "IfElse", "", testPass, false, "test").
+		IfElse("total > 10", 1, 2).
+		StepFn("Small", "", testStep("small", true), false).
+		StepFn("Big", "", testStep("big", true), false).
+		StepFn("End", "", testStep("end", true), false)
+
+	// Con indice se continua en el step siguiente al destino
+	ctx := testVisited(t, "ifelse-index", "ifelse_index", et.Json{"total": 20})
+	if ctx["small"] != nil || ctx["big"] != true || ctx["end"] != true {
+		t.Fatalf("goto 1 must continue at Big, ctx %v", ctx)
+	}
+}
+
+func TestSwitchByNameRunsTarget(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("switch_name", "v1", "Switch", "", testPass, false, "test").
+		SwitchByName("kind", map[string]string{"a": "A", "b": "B"}, "End").
+		StepFn("A", "", testStep("a", true), true).
+		StepFn("B", "", testStep("b", true), false).
+		StepFn("End", "", testStep("end", true), false)
+
+	ctx := testVisited(t, "switch-b", "switch_name", et.Json{"kind": "b"})
+	if ctx["a"] != nil || ctx["b"] != true || ctx["end"] != true {
+		t.Fatalf("case b must run B and End, ctx %v", ctx)
+	}
+
+	ctx = testVisited(t, "switch-default", "switch_name", et.Json{"kind": "x"})
+	if ctx["a"] != nil || ctx["b"] != nil || ctx["end"] != true {
+		t.Fatalf("default must run End only, ctx %v", ctx)
+	}
+}
+
+func TestSwitchByIndexRunsTarget(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("switch_index", "v1", "Switch", "", testPass, false, "test").
+		Switch("kind", map[string]int{"a": 1, "b": 2}, -1).
+		StepFn("A", "", testStep("a", true), true).
+		StepFn("B", "", testStep("b", true), false).
+		StepFn("End", "", testStep("end", true), false)
+
+	ctx := testVisited(t, "switch-index-b", "switch_index", et.Json{"kind": "b"})
+	if ctx["a"] != nil || ctx["b"] != true || ctx["end"] != true {
+		t.Fatalf("case b must run B and End, ctx %v", ctx)
+	}
+
+	// A tiene stop, la instancia queda pendiente despues de A
+	_, err := Run("switch-index-a", "switch_index", 0, et.Json{}, et.Json{"kind": "a"}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	ctx = waitStatus(t, "switch-index-a", FlowStatusPending).Ctx
+	if ctx["a"] != true || ctx["b"] != nil || ctx["end"] != nil {
+		t.Fatalf("case a must run A and stop, ctx %v", ctx)
+	}
+
+	// Sin caso y default -1 se continua con el step siguiente
+	_, err = Run("switch-index-x", "switch_index", 0, et.Json{}, et.Json{"kind": "x"}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	ctx = waitStatus(t, "switch-index-x", FlowStatusPending).Ctx
+	if ctx["a"] != true || ctx["b"] != nil {
+		t.Fatalf("default -1 must continue with A, ctx %v", ctx)
+	}
+}
+
+func TestConflictAbortsStaleRun(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wf.newFlowFn("conflict", "v1", "Conflict", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		close(started)
+		<-release
+		return ctx, nil
+	}, false, "test").
+		StepFn("End", "", testStep("end", true), false)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run("conflict-1", "conflict", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	// Otro proceso guarda la instancia mientras el step esta en ejecucion
+	other, err := getStore().GetInstance("conflict-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	other.Revision++
+	other.PinnedData = et.Json{"writer": "other"}
+	if err := getStore().SetInstance(other, other.Revision-1, &Changes{}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	close(release)
+	err = <-done
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("the stale run must stop with a conflict, got %v", err)
+	}
+
+	result, err := getStore().GetInstance("conflict-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if result.Revision != other.Revision || result.PinnedData.Str("writer") != "other" || result.Ctx["end"] != nil {
+		t.Fatal("the stale run must not overwrite the newer revision or run more steps")
+	}
+}
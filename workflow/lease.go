@@ -0,0 +1,279 @@
+package workflow
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cgalvisleon/et/cache"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/timezone"
+)
+
+const (
+	leaseKey        = "workflow:lease"
+	leaseDefaultTTL = time.Minute
+)
+
+type Lease interface {
+	Acquire(instanceId, owner string, ttl time.Duration) (bool, error)
+	Renew(instanceId, owner string, ttl time.Duration) (bool, error)
+	Release(instanceId, owner string) error
+}
+
+type memoryItem struct {
+	owner     string
+	expiresAt time.Time
+}
+
+type memoryLease struct {
+	items map[string]*memoryItem
+	mu    sync.Mutex
+}
+
+/**
+* NewMemoryLease
+* Lease local, solo protege las instancias dentro del proceso
+* @return Lease
+**/
+func NewMemoryLease() Lease {
+	return &memoryLease{
+		items: make(map[string]*memoryItem),
+		mu:    sync.Mutex{},
+	}
+}
+
+/**
+* Acquire
+* @param instanceId, owner string, ttl time.Duration
+* @return bool, error
+**/
+func (s *memoryLease) Acquire(instanceId, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timezone.NowTime()
+	item, ok := s.items[instanceId]
+	if ok && item.owner != owner && now.Before(item.expiresAt) {
+		return false, nil
+	}
+
+	s.items[instanceId] = &memoryItem{
+		owner:     owner,
+		expiresAt: now.Add(ttl),
+	}
+
+	return true, nil
+}
+
+/**
+* Renew
+* @param instanceId, owner string, ttl time.Duration
+* @return bool, error
+**/
+func (s *memoryLease) Renew(instanceId, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[instanceId]
+	if !ok || item.owner != owner {
+		return false, nil
+	}
+
+	item.expiresAt = timezone.NowTime().Add(ttl)
+	return true, nil
+}
+
+/**
+* Release
+* @param instanceId, owner string
+* @return error
+**/
+func (s *memoryLease) Release(instanceId, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[instanceId]
+	if ok && item.owner == owner {
+		delete(s.items, instanceId)
+	}
+
+	return nil
+}
+
+type cacheLease struct{}
+
+/**
+* NewCacheLease
+* Lease distribuido en cache, el primer Incr de la llave gana y la expiracion libera workers caidos
+* @return Lease
+**/
+func NewCacheLease() Lease {
+	return &cacheLease{}
+}
+
+/**
+* keys
+* @param instanceId string
+* @return string, string
+**/
+func (s *cacheLease) keys(instanceId string) (string, string) {
+	key := fmt.Sprintf("%s:%s", leaseKey, instanceId)
+	return key, key + ":owner"
+}
+
+/**
+* Acquire
+* @param instanceId, owner string, ttl time.Duration
+* @return bool, error
+**/
+func (s *cacheLease) Acquire(instanceId, owner string, ttl time.Duration) (bool, error) {
+	key, ownerKey := s.keys(instanceId)
+	n := cache.Incr(key, ttl)
+	if n == 0 {
+		return false, fmt.Errorf(MSG_LEASE_UNAVAILABLE)
+	}
+
+	if n == 1 {
+		cache.Set(ownerKey, owner, ttl)
+		return true, nil
+	}
+
+	return s.Renew(instanceId, owner, ttl)
+}
+
+/**
+* Renew
+* @param instanceId, owner string, ttl time.Duration
+* @return bool, error
+**/
+func (s *cacheLease) Renew(instanceId, owner string, ttl time.Duration) (bool, error) {
+	key, ownerKey := s.keys(instanceId)
+	current, err := cache.Get(ownerKey, "")
+	if err != nil {
+		return false, err
+	}
+
+	if current != owner {
+		return false, nil
+	}
+
+	err = cache.Expire(key, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	err = cache.Expire(ownerKey, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+/**
+* Release
+* @param instanceId, owner string
+* @return error
+**/
+func (s *cacheLease) Release(instanceId, owner string) error {
+	key, ownerKey := s.keys(instanceId)
+	current, err := cache.Get(ownerKey, "")
+	if err != nil {
+		return err
+	}
+
+	if current != owner {
+		return nil
+	}
+
+	_, err = cache.Delete(ownerKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = cache.Delete(key)
+	return err
+}
+
+/**
+* acquire
+* Bloqueo en proceso de la instancia y lease para que un solo worker la ejecute,
+* el lease se renueva mientras la instancia este en ejecucion y si se pierde la ejecucion se aborta
+* @param instance *Instance
+* @return func(), error
+**/
+func (s *WorkFlows) acquire(instance *Instance) (func(), error) {
+	if !instance.mu.TryLock() {
+		return nil, errorInstanceRunning
+	}
+	instance.ctxMu.Lock()
+	instance.aborted = nil
+	instance.ctxMu.Unlock()
+
+	s.mu.Lock()
+	lease, ttl := s.lease, s.leaseTTL
+	s.mu.Unlock()
+
+	ok, err := lease.Acquire(instance.Id, s.owner, ttl)
+	if err != nil {
+		instance.mu.Unlock()
+		return nil, err
+	}
+
+	if !ok {
+		instance.mu.Unlock()
+		return nil, fmt.Errorf(MSG_INSTANCE_LEASED, instance.Id)
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(ttl / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ok, err := lease.Renew(instance.Id, s.owner, ttl)
+				if err != nil {
+					logs.Error(err)
+				} else if !ok {
+					logs.Logf(packageName, MSG_INSTANCE_LEASE_LOST, instance.Id)
+					instance.abort(fmt.Errorf(MSG_INSTANCE_LEASE_LOST, instance.Id))
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		err := lease.Release(instance.Id, s.owner)
+		if err != nil {
+			logs.Error(err)
+		}
+		instance.mu.Unlock()
+	}, nil
+}
+
+/**
+* setLease
+* @param lease Lease, ttl time.Duration
+**/
+func (s *WorkFlows) setLease(lease Lease, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lease == nil {
+		lease = NewMemoryLease()
+	}
+
+	if ttl <= 0 {
+		ttl = leaseDefaultTTL
+	}
+
+	s.lease = lease
+	s.leaseTTL = ttl
+}
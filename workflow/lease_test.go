@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestLeaseBlocksSecondRunner(t *testing.T) {
+	first := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	block := func(flow *Instance, ctx et.Json) (et.Json, error) {
+		close(started)
+		<-release
+		return ctx, nil
+	}
+	first.newFlowFn("lease", "v1", "Lease", "", block, false, "test")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := first.run("lease-1", "lease", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	// Otro worker con el mismo lease y el mismo store
+	second := newWorkFlows()
+	second.setLease(first.lease, time.Minute)
+	second.newFlowFn("lease", "v1", "Lease", "", testPass, false, "test")
+	_, err := second.continueContext(context.Background(), "lease-1", et.Json{}, et.Json{}, "test")
+	if err == nil || err.Error() != fmt.Sprintf(MSG_INSTANCE_LEASED, "lease-1") {
+		t.Fatalf("a second runner must be rejected by the lease, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("first runner: %v", err)
+	}
+	waitStatus(t, "lease-1", FlowStatusDone)
+
+	ok, err := first.lease.Acquire("lease-1", "other", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("the lease must be released when the run ends, got %v %v", ok, err)
+	}
+}
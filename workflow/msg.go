@@ -1,43 +1,121 @@
 package workflow
 
 const (
-	MSG_FLOW_CREATED                = "Flujo definido Tag:%s version:%s name:%s"
-	MSG_FLOW_NOT_FOUND              = "Flujo no encontrado"
-	MSG_FLOW_NOT_INSTANCE           = "Flujo no instanciado"
-	MSG_START_WORKFLOW              = "Iniciando el workflow"
-	MSG_INSTANCE_ID_REQUIRED        = "Instance id es requerido"
-	MSG_INSTANCE_NOT_FOUND          = "Instancia no encontrada"
-	MSG_INSTANCE_EXISTS             = "Instancia existe"
-	MSG_INSTANCE_FAILED             = "Instancia fallido:%s Tag:%s status:%s, step:%d error:%s"
-	MSG_INSTANCE_STATUS             = "Instancia:%s Tag:%s status:%s, step:%d"
-	MSG_INSTANCE_DEBUG              = "Instancia:%s debug:%s"
-	MSG_INSTANCE_GOTO               = "Instancia %s Tag:%s ir al step:%d %s"
-	MSG_INSTANCE_LOAD               = "Instancia load:%s tag:%s currentStep:%d"
-	MSG_INSTANCE_ALREADY_DONE       = "Instancia ya finalizada"
-	MSG_INSTANCE_ALREADY_RUNNING    = "Instancia ya en ejecucion"
-	MSG_INSTANCE_PENDING            = "Instancia pendiente"
-	MSG_INSTANCE_WORKFLOWS_IS_NIL   = "workFlows es nil"
-	MSG_ID_REQUIRED                 = "id es requerido"
-	MSG_INSTANCE_EXPRESSION_TRUE    = "Resultado de la expresion es true"
-	MSG_INSTANCE_EXPRESSION_FALSE   = "Resultado de la expresion es false"
-	MSG_INSTANCE_ROLLBACK           = "Esta intentando hacer rollback de un step que no existe"
-	MSG_INSTANCE_ROLLBACK_STEP      = "Haciendo rollback del step:%d"
-	MSG_INSTANCE_STEP_CREATED       = "Definido step:%d name:%s Tag:%s"
-	MSG_INSTANCE_ROLLBACK_CREATED   = "Definido rollback step:%d name:%s Tag:%s"
-	MSG_INSTANCE_ROLLBACK_FAILED    = "Rollback Instance:%s Tag:%s status:%s, step:%d error:%s"
-	MSG_INSTANCE_CONSISTENCY        = "Consistencia definida Tag:%s consistency:%s"
-	MSG_INSTANCE_RESILIENCE         = "Definida resilencia Tag:%s totalAttempts:%d timeAttempts:%s retentionTime:%s"
-	MSG_INSTANCE_IFELSE             = "Definido ifElse step:%d name:%s expresion:%s ? %d : %d Tag:%s"
-	MSG_INSTANCE_RETENTION          = "Definida retencion Tag:%s retentionTime:%s"
-	MSG_INSTANCE_GOTO_USER_DECISION = "Por desicion del usuario"
-	MSG_WORKFLOW_DELETE             = "Workflow eliminado Tag:%s"
-	MSG_WORKFLOW_DONE_INSTANCE      = "Instancia terminada:%s"
-	MSG_WORKFLOW_LIMIT_REQUESTS     = "Límite de peticiones alcanzado, el proceso se pondra en espera para ejecusión, instanceId:%s"
-	MSG_INSTANCE_RUN                = "Run instance:%s, flow:%s"
-	MSG_INSTANCE_INSTANCE_INC       = "WorkFlows.instanceInc totalInstances:%d limitRequests:%d"
-	MSG_INSTANCE_INSTANCE_DEC       = "WorkFlows.instanceDec totalInstances:%d limitRequests:%d"
-	MSG_INSTANCE_EVALUATE           = "error al evaluar expresion:%s, error:%s"
-	MSG_ARG_REQUIRED                = "argumento requerido:%s"
-	MSG_INSTANCE_DEFINITION_EMPTY   = "definition is empty"
-	MSG_ATTRIBUTE_REQUIRED_STEP     = "atributo requerido:%s step:%d"
+	MSG_FLOW_CREATED                 = "Flujo definido Tag:%s version:%s name:%s"
+	MSG_FLOW_NOT_FOUND               = "Flujo no encontrado"
+	MSG_FLOW_NOT_INSTANCE            = "Flujo no instanciado"
+	MSG_START_WORKFLOW               = "Iniciando el workflow"
+	MSG_INSTANCE_ID_REQUIRED         = "Instance id es requerido"
+	MSG_INSTANCE_NOT_FOUND           = "Instancia no encontrada"
+	MSG_INSTANCE_EXISTS              = "Instancia existe"
+	MSG_INSTANCE_FAILED              = "Instancia fallido:%s Tag:%s status:%s, step:%d error:%s"
+	MSG_INSTANCE_STATUS              = "Instancia:%s Tag:%s status:%s, step:%d"
+	MSG_INSTANCE_DEBUG               = "Instancia:%s debug:%s"
+	MSG_INSTANCE_GOTO                = "Instancia %s Tag:%s ir al step:%d %s"
+	MSG_INSTANCE_LOAD                = "Instancia load:%s tag:%s currentStep:%d"
+	MSG_INSTANCE_ALREADY_DONE        = "Instancia ya finalizada"
+	MSG_INSTANCE_ALREADY_RUNNING     = "Instancia ya en ejecucion"
+	MSG_INSTANCE_PENDING             = "Instancia pendiente"
+	MSG_INSTANCE_WORKFLOWS_IS_NIL    = "workFlows es nil"
+	MSG_ID_REQUIRED                  = "id es requerido"
+	MSG_INSTANCE_EXPRESSION_TRUE     = "Resultado de la expresion es true"
+	MSG_INSTANCE_EXPRESSION_FALSE    = "Resultado de la expresion es false"
+	MSG_INSTANCE_ROLLBACK            = "Esta intentando hacer rollback de un step que no existe"
+	MSG_INSTANCE_ROLLBACK_STEP       = "Haciendo rollback del step:%d"
+	MSG_INSTANCE_STEP_CREATED        = "Definido step:%d name:%s Tag:%s"
+	MSG_INSTANCE_ROLLBACK_CREATED    = "Definido rollback step:%d name:%s Tag:%s"
+	MSG_INSTANCE_ROLLBACK_FAILED     = "Rollback Instance:%s Tag:%s status:%s, step:%d error:%s"
+	MSG_INSTANCE_CONSISTENCY         = "Consistencia definida Tag:%s consistency:%s"
+	MSG_INSTANCE_RESILIENCE          = "Definida resilencia Tag:%s totalAttempts:%d timeAttempts:%s retentionTime:%s"
+	MSG_INSTANCE_IFELSE              = "Definido ifElse step:%d name:%s expresion:%s ? %d : %d Tag:%s"
+	MSG_INSTANCE_RETENTION           = "Definida retencion Tag:%s retentionTime:%s"
+	MSG_INSTANCE_GOTO_USER_DECISION  = "Por desicion del usuario"
+	MSG_WORKFLOW_DELETE              = "Workflow eliminado Tag:%s"
+	MSG_WORKFLOW_DONE_INSTANCE       = "Instancia terminada:%s"
+	MSG_WORKFLOW_LIMIT_REQUESTS      = "Límite de peticiones alcanzado, el proceso se pondra en espera para ejecusión, instanceId:%s"
+	MSG_INSTANCE_RUN                 = "Run instance:%s, flow:%s"
+	MSG_INSTANCE_INSTANCE_INC        = "WorkFlows.instanceInc totalInstances:%d limitRequests:%d"
+	MSG_INSTANCE_INSTANCE_DEC        = "WorkFlows.instanceDec totalInstances:%d limitRequests:%d"
+	MSG_INSTANCE_EVALUATE            = "error al evaluar expresion:%s, error:%s"
+	MSG_ARG_REQUIRED                 = "argumento requerido:%s"
+	MSG_INSTANCE_DEFINITION_EMPTY    = "definition is empty"
+	MSG_ATTRIBUTE_REQUIRED_STEP      = "atributo requerido:%s step:%d"
+	MSG_INSTANCE_PARALLEL_CREATED    = "Definido parallel step:%d name:%s wait:%d merge:%s Tag:%s"
+	MSG_INSTANCE_BRANCH_CREATED      = "Definido branch:%s step:%d name:%s Tag:%s"
+	MSG_INSTANCE_NOT_PARALLEL        = "El step:%d name:%s no es parallel"
+	MSG_INSTANCE_PARALLEL_EMPTY      = "El step parallel:%s no tiene branches"
+	MSG_INSTANCE_PARALLEL_FAILED     = "Fallo el step parallel:%s error:%s"
+	MSG_INSTANCE_ROLLBACK_BRANCH     = "Haciendo rollback del step:%d branch:%s"
+	MSG_INSTANCE_IFELSE_BY_NAME      = "Definido ifElse step:%d name:%s expresion:%s ? %s : %s Tag:%s"
+	MSG_STEP_NOT_FOUND               = "Step no encontrado:%s Tag:%s"
+	MSG_MODEL_NOT_FOUND              = "Modelo no encontrado database:%s name:%s"
+	MSG_STEP_DUPLICATED              = "Step duplicado:%s Tag:%s"
+	MSG_INSTANCE_SWITCH              = "Definido switch step:%d name:%s expresion:%s cases:%d default:%d Tag:%s"
+	MSG_INSTANCE_SWITCH_BY_NAME      = "Definido switch step:%d name:%s expresion:%s cases:%d default:%s Tag:%s"
+	MSG_INSTANCE_SWITCH_CASE         = "Resultado del switch es %s"
+	MSG_INSTANCE_FOREACH_CREATED     = "Definido forEach step:%d name:%s path:%s concurrency:%d stopOnError:%t Tag:%s"
+	MSG_INSTANCE_BODY_CREATED        = "Definido body:%s step:%d name:%s Tag:%s"
+	MSG_INSTANCE_NOT_FOREACH         = "El step:%d name:%s no es forEach"
+	MSG_INSTANCE_FOREACH_EMPTY       = "El step forEach:%s no tiene body"
+	MSG_INSTANCE_FOREACH_PATH        = "La ruta:%s no es un arreglo en el ctx, step:%s"
+	MSG_INSTANCE_FOREACH_FAILED      = "Fallo el step forEach:%s error:%s"
+	MSG_INSTANCE_SUBFLOW_CREATED     = "Definido subFlow step:%d name:%s flow:%s Tag:%s"
+	MSG_INSTANCE_SUBFLOW_FAILED      = "Fallo el subflujo instancia:%s Tag:%s error:%s"
+	MSG_INSTANCE_WAITING             = "Instancia:%s Tag:%s en espera en el step:%d"
+	MSG_INSTANCE_SIGNAL_CREATED      = "Definido waitSignal step:%d name:%s signal:%s timeout:%s Tag:%s"
+	MSG_INSTANCE_SIGNAL              = "Instancia:%s Tag:%s recibio la señal:%s"
+	MSG_INSTANCE_SIGNAL_QUEUED       = "Señal encolada, instancia en ejecucion instanceId:%s signal:%s"
+	MSG_INSTANCE_SIGNAL_TIMEOUT      = "Tiempo de espera agotado para la señal:%s step:%s"
+	MSG_INSTANCE_SLEEP_CREATED       = "Definido sleep step:%d name:%s duration:%s Tag:%s"
+	MSG_INSTANCE_SLEEP_UNTIL_CREATED = "Definido sleepUntil step:%d name:%s until:%s Tag:%s"
+	MSG_TIMERS_RESTORED              = "Timers pendientes restaurados:%d"
+	MSG_FLOW_WITHOUT_STEPS           = "El flujo:%s no tiene steps"
+	MSG_VALIDATE_EMPTY_FLOW          = "El flujo:%s no tiene steps"
+	MSG_VALIDATE_NIL_STEP            = "step es nil"
+	MSG_VALIDATE_REQUIRED            = "atributo requerido:%s"
+	MSG_VALIDATE_GOTO_OUT_OF_RANGE   = "goto:%d fuera de rango, total steps:%d"
+	MSG_VALIDATE_COMPILE             = "error al compilar definition:%s"
+	MSG_VALIDATE_FUNCTION_NIL        = "el step de tipo function no tiene funcion"
+	MSG_VALIDATE_INVALID_TYPE        = "tipo de step invalido:%s"
+	MSG_VALIDATE_UNREACHABLE         = "step inalcanzable"
+	MSG_VALIDATE_INVALID_TIMEOUT     = "timeout invalido:%s, debe ser mayor o igual a cero"
+	MSG_VALIDATE_BRANCH_OPTION       = "el branch:%s no admite:%s"
+	MSG_SPEC_FUNCTION_NOT_REGISTERED = "El step:%s usa una funcion Go sin nombre registrado, use RegisterFn"
+	MSG_SPEC_FORMAT_UNSUPPORTED      = "Formato no soportado:%s"
+	MSG_SPEC_VERSION_UNSUPPORTED     = "Version de especificacion no soportada:%s, se espera:%s"
+	MSG_INSTANCE_CANCELLED           = "Instancia cancelada"
+	MSG_INSTANCE_TIMEOUT_CREATED     = "Definido timeout step:%d name:%s timeout:%s Tag:%s"
+	MSG_INSTANCE_STEP_TIMEOUT        = "Tiempo de ejecucion agotado step:%s timeout:%s"
+	MSG_INSTANCE_RETRY_CREATED       = "Definido retry step:%d name:%s max_attempts:%d Tag:%s"
+	MSG_INSTANCE_STEP_RETRY          = "Reintentando step:%s attempt:%d delay:%s error:%s"
+	MSG_INSTANCE_STEP_RETRY_HISTORY  = "intento:%d error:%s"
+	MSG_LEASE_UNAVAILABLE            = "Lease no disponible, cache no conectada"
+	MSG_INSTANCE_LEASED              = "Instancia en ejecucion en otro worker, instanceId:%s"
+	MSG_INSTANCE_LEASE_LOST          = "Lease perdido, instanceId:%s"
+	MSG_CACHE_UNAVAILABLE            = "Cache no conectada"
+	MSG_IDEMPOTENCY_CONFLICT         = "Llave de idempotencia:%s usada con otro payload Tag:%s"
+	MSG_IDEMPOTENCY_IN_PROGRESS      = "Llave de idempotencia:%s en registro Tag:%s"
+	MSG_INSTANCE_ALREADY_EXISTS      = "Instancia ya existe, instanceId:%s"
+	MSG_INSTANCE_REPLAY_NOT_RECORDED = "Step:%d sin ctx registrado en la instancia:%s"
+	MSG_INSTANCE_REPLAY              = "Replay instanceId:%s de:%s desde step:%d"
+	MSG_INSTANCE_INPUT_CREATED       = "Definido input step:%d name:%s input:%v Tag:%s"
+	MSG_INSTANCE_OUTPUT_CREATED      = "Definido output step:%d name:%s output:%v Tag:%s"
+	MSG_INSTANCE_ON_ERROR_CREATED    = "Definido on_error step:%d name:%s go_to_step:%s Tag:%s"
+	MSG_INSTANCE_ON_ERROR            = "Por error: %s"
+	MSG_ON_ERROR_WITHOUT_TARGET      = "on_error sin go_to step:%s Tag:%s"
+	MSG_INSTANCE_COMPENSATION_FAILED = "Compensacion fallida instanceId:%s step:%d error:%s"
+	MSG_INSTANCE_ROLLED_BACK         = "Instancia compensada, status:%s"
+	MSG_INSTANCE_ROLLBACK_STATUS     = "No se puede compensar una instancia con status:%s"
+	MSG_ROLLBACKS_RESTORED           = "Compensaciones restauradas:%d"
+	MSG_STORE_UNSUPPORTED            = "Operacion no soportada por el store:%s"
+	MSG_STORE_LOG_CORRUPT            = "Registro corrupto instanceId:%s linea:%d error:%s"
+	MSG_STORE_LOG_TRUNCATED          = "Registro incompleto ignorado instanceId:%s linea:%d"
+	MSG_STORE_LOG_REPAIRED           = "Registro incompleto eliminado instanceId:%s bytes:%d"
+	MSG_STORE_HOOKS_IGNORED          = "Hay un store definido con SetStore, las funciones On* no se usan"
+	MSG_INSTANCES_RESTORED           = "Instancias restauradas:%d"
+	MSG_INSTANCE_CONFLICT            = "Conflicto de revision instanceId:%s esperada:%d actual:%d"
+	MSG_INSTANCE_CONFLICT_RETRY      = "Conflicto de revision instanceId:%s, recargando intento:%d"
+	MSG_INSTANCE_GOTO_HISTORY        = "step:%d %s"
+	MSG_INSTANCE_ROLLBACK_HISTORY    = "step:%s error:%s"
+	MSG_INSTANCE_REPLAY_HISTORY      = "de:%s desde step:%d"
 )
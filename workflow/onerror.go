@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+type OnError struct {
+	Code     string `json:"code"`
+	Pattern  string `json:"pattern"`
+	GoTo     int    `json:"go_to"`
+	GoToStep string `json:"go_to_step"`
+	Expose   bool   `json:"expose"`
+}
+
+/**
+* UnmarshalJSON
+* Sin go_to ni go_to_step el manejador queda sin destino y no se usa
+* @param data []byte
+* @return error
+**/
+func (s *OnError) UnmarshalJSON(data []byte) error {
+	type onError OnError
+	s.GoTo = -1
+
+	return json.Unmarshal(data, (*onError)(s))
+}
+
+/**
+* match
+* Sin codigo ni patron atrapa cualquier error
+* @param err error
+* @return bool
+**/
+func (s *OnError) match(err error) bool {
+	if s.Code != "" && s.Code != errorCode(err) {
+		return false
+	}
+
+	if s.Pattern == "" {
+		return true
+	}
+
+	ok, e := regexp.MatchString(s.Pattern, err.Error())
+	return e == nil && ok
+}
+
+/**
+* catch
+* Primer manejador que coincide con el error, nil si el error debe ir al rollback,
+* un manejador sin destino valido no atrapa el error
+* @param err error, n int
+* @return *OnError
+**/
+func (s *Step) catch(err error, n int) *OnError {
+	for _, handler := range s.OnError {
+		if handler.GoTo < 0 || handler.GoTo >= n {
+			continue
+		}
+
+		if handler.match(err) {
+			return handler
+		}
+	}
+
+	return nil
+}
+
+/**
+* setCatch
+* Registra el error en el resultado del step y continua en el step manejador
+* @param step *Step, handler *OnError, err error
+* @return et.Json, error
+**/
+func (s *Instance) setCatch(step *Step, handler *OnError, err error) (et.Json, error) {
+	result := et.Json{}
+	if handler.Expose {
+		result["error"] = et.Json{
+			"step":    step.Name,
+			"code":    errorCode(err),
+			"message": err.Error(),
+		}
+	}
+
+	return s.setGoto(handler.GoTo, fmt.Sprintf(MSG_INSTANCE_ON_ERROR, err.Error()), result, err)
+}
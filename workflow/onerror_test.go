@@ -0,0 +1,75 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testOnErrorFlow
+* Charge falla con el codigo recibido en ctx.code
+* @param wf *WorkFlows, tag, code, pattern string
+* @return *Flow
+**/
+func testOnErrorFlow(wf *WorkFlows, tag, code, pattern string) *Flow {
+	return wf.newFlowFn(tag, "v1", "OnError", "", testPass, false, "test").
+		StepFn("Charge", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+			return et.Json{}, NewError(ctx.Str("code"), "card %s declined", "visa")
+		}, false).
+		OnError(code, pattern, "Rejected", true).
+		StepFn("Approved", "", testStep("approved", true), true).
+		StepFn("Rejected", "", testStep("rejected", true), false)
+}
+
+func TestOnErrorRoutesByCode(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testOnErrorFlow(wf, "on_error_code", "insufficient_funds", "")
+
+	ctx := testVisited(t, "on-error-1", "on_error_code", et.Json{"code": "insufficient_funds"})
+	if !ctx.Bool("rejected") || ctx.Bool("approved") {
+		t.Fatalf("the handled error must go to Rejected, ctx %v", ctx)
+	}
+
+	exposed := ctx.Json("error")
+	if exposed.Str("code") != "insufficient_funds" || exposed.Str("step") != "Charge" {
+		t.Fatalf("the error must be exposed in ctx, got %v", exposed)
+	}
+
+	history, err := History("on-error-1")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	found := false
+	for _, entry := range history {
+		found = found || entry.Type == HistoryStepFailed
+	}
+	if !found {
+		t.Fatal("the handled failure must stay in the history")
+	}
+}
+
+func TestOnErrorRoutesByPattern(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testOnErrorFlow(wf, "on_error_pattern", "", "declined$")
+
+	ctx := testVisited(t, "on-error-2", "on_error_pattern", et.Json{"code": "other"})
+	if !ctx.Bool("rejected") {
+		t.Fatalf("the pattern must route the error to Rejected, ctx %v", ctx)
+	}
+}
+
+func TestOnErrorUnmatchedRollsBack(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testOnErrorFlow(wf, "on_error_miss", "insufficient_funds", "")
+
+	_, err := Run("on-error-3", "on_error_miss", 0, et.Json{}, et.Json{"code": "timeout"}, "test")
+	if errorCode(err) != "timeout" {
+		t.Fatalf("an unhandled error must be returned, got %v", err)
+	}
+
+	result := waitStatus(t, "on-error-3", FlowStatusFailed)
+	if result.Ctx.Bool("rejected") {
+		t.Fatal("an unhandled error must not go to the handler")
+	}
+}
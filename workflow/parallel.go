@@ -0,0 +1,304 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/workflow/vm"
+)
+
+type TpMerge string
+
+const (
+	TpMergeAll       TpMerge = "all"
+	TpMergeNamespace TpMerge = "namespace"
+	TpMergeFirst     TpMerge = "first"
+)
+
+type branchResult struct {
+	idx    int
+	ctx    et.Json
+	result et.Json
+	err    error
+}
+
+/**
+* newStepParallel
+* @param name, description string, wait int, merge TpMerge, stop bool
+* @return *Step
+**/
+func newStepParallel(name, description string, wait int, merge TpMerge, stop bool) (*Step, error) {
+	if merge == "" {
+		merge = TpMergeAll
+	}
+
+	result := &Step{
+		Name:        name,
+		Description: description,
+		Type:        TpParallel,
+		Stop:        stop,
+		Wait:        wait,
+		Merge:       merge,
+		Branches:    make([]*Step, 0),
+	}
+	result.fn = result.runParallel
+
+	return result, nil
+}
+
+/**
+* runIsolated
+* Los steps de tipo definition se ejecutan en su propia vm, goja no es seguro entre goroutines,
+* las funciones Go solo deben usar el ctx recibido y flow.Context(), en parallel reciben una copia
+* de la instancia y sus cambios se descartan
+* @param flow *Instance, ctx et.Json, vars et.Json
+* @return et.Json, error
+**/
+func (s *Step) runIsolated(flow *Instance, ctx et.Json, vars et.Json) (et.Json, error) {
+	if s.Type == TpDefinition {
+		v := vm.New()
+		for k, val := range vars {
+			v.Set(k, plain(val))
+		}
+
+		return flow.runDefinition(v, s.Definition, ctx)
+	}
+
+	if s.fn == nil {
+		return ctx, fmt.Errorf("step function is nil for branch: %s at index %d", s.Name, flow.Current)
+	}
+
+	return s.fn(flow, ctx)
+}
+
+/**
+* runBranch
+* El branch corre sobre su copia de la instancia, con Timeout falla al vencer el plazo aunque
+* la funcion no termine, la copia se descarta
+* @param fork *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runBranch(fork *Instance, ctx et.Json) (et.Json, error) {
+	if s.Timeout <= 0 {
+		return s.runIsolated(fork, ctx, nil)
+	}
+
+	c, cancel := context.WithTimeout(fork.Context(), s.Timeout)
+	defer cancel()
+	fork.withContext(c)
+
+	done := make(chan *resultFn, 1)
+	go func() {
+		result, err := s.runIsolated(fork, ctx, nil)
+		done <- &resultFn{Result: result, Error: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.Result, res.Error
+	case <-c.Done():
+		select {
+		case res := <-done:
+			return res.Result, res.Error
+		default:
+		}
+	}
+
+	err := c.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = NewError(ErrorCodeTimeout, MSG_INSTANCE_STEP_TIMEOUT, s.Name, s.Timeout)
+	}
+	fork.abort(err)
+
+	return et.Json{}, err
+}
+
+/**
+* runParallel
+* Ejecuta los branches concurrentemente y espera a todos o a Wait de ellos, al completar Wait
+* o al no poder completarlo se cancela el contexto de los branches restantes y se esperan todos
+* antes de unir los resultados, asi todo branch queda registrado y se puede compensar
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runParallel(flow *Instance, ctx et.Json) (et.Json, error) {
+	total := len(s.Branches)
+	if total == 0 {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_PARALLEL_EMPTY, s.Name)
+	}
+
+	wait := s.Wait
+	if wait <= 0 || wait > total {
+		wait = total
+	}
+
+	c, cancel := context.WithCancel(flow.Context())
+	results := make([]*branchResult, total)
+	ch := make(chan int, total)
+	for i, branch := range s.Branches {
+		results[i] = &branchResult{idx: i, ctx: ctx.Clone()}
+		fork := flow.fork(c)
+		go func(res *branchResult, branch *Step, fork *Instance) {
+			res.result, res.err = branch.runBranch(fork, res.ctx.Clone())
+			ch <- res.idx
+		}(results[i], branch, fork)
+	}
+
+	succeeded := 0
+	failed := 0
+	order := make([]int, 0, total)
+	for range s.Branches {
+		idx := <-ch
+		order = append(order, idx)
+		if results[idx].err != nil {
+			failed++
+		} else {
+			succeeded++
+		}
+
+		if succeeded >= wait || failed > total-wait {
+			cancel()
+		}
+	}
+	cancel()
+
+	attempt := 0
+	if flow.resilence != nil {
+		attempt = flow.resilence.Attempt
+	}
+
+	branches := make(map[string]*Result)
+	done := make([]*branchResult, 0)
+	var err error
+	for _, idx := range order {
+		res := results[idx]
+		errMessage := ""
+		if res.err != nil {
+			errMessage = res.err.Error()
+			if err == nil {
+				err = res.err
+			}
+		} else {
+			done = append(done, res)
+		}
+
+		branches[s.Branches[idx].Name] = &Result{
+			Step:    flow.Current,
+			Ctx:     res.ctx,
+			Attempt: attempt,
+			Result:  res.result,
+			Error:   errMessage,
+		}
+	}
+
+	flow.Results[flow.Current] = &Result{
+		Step:     flow.Current,
+		Ctx:      ctx.Clone(),
+		Attempt:  attempt,
+		Branches: branches,
+	}
+	delete(flow.Rollbacks, flow.Current)
+
+	if len(done) < wait {
+		flow.rollbackBranches(flow.Current, s)
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_PARALLEL_FAILED, s.Name, err.Error())
+	}
+
+	return s.merge(done), nil
+}
+
+/**
+* merge
+* @param done []*branchResult
+* @return et.Json
+**/
+func (s *Step) merge(done []*branchResult) et.Json {
+	result := et.Json{}
+	switch s.Merge {
+	case TpMergeFirst:
+		for k, v := range done[0].result {
+			result[k] = v
+		}
+	case TpMergeNamespace:
+		for _, res := range done {
+			result[s.Branches[res.idx].Name] = res.result
+		}
+	default:
+		ordered := append([]*branchResult{}, done...)
+		sort.Slice(ordered, func(i, j int) bool {
+			return ordered[i].idx < ordered[j].idx
+		})
+		for _, res := range ordered {
+			for k, v := range res.result {
+				result[k] = v
+			}
+		}
+	}
+
+	return result
+}
+
+/**
+* rollbackBranches
+* Compensa los branches completados del step idx en orden inverso, los que ya se compensaron
+* bien en un intento anterior no se vuelven a compensar
+* @param idx int, step *Step
+**/
+func (s *Instance) rollbackBranches(idx int, step *Step) error {
+	res := s.Results[idx]
+	if res == nil || res.Branches == nil {
+		return nil
+	}
+
+	var failed error
+	outcomes := make(map[string]*Result)
+	if prev := s.Rollbacks[idx]; prev != nil {
+		maps.Copy(outcomes, prev.Branches)
+	}
+	for i := len(step.Branches) - 1; i >= 0; i-- {
+		branch := step.Branches[i]
+		if branch.rollbacks == nil {
+			continue
+		}
+
+		branchResult := res.Branches[branch.Name]
+		if branchResult == nil || branchResult.Error != "" {
+			continue
+		}
+
+		if done := outcomes[branch.Name]; done != nil && done.Error == "" {
+			continue
+		}
+
+		logs.Logf(packageName, MSG_INSTANCE_ROLLBACK_BRANCH, idx, branch.Name)
+		ctx := branchResult.Ctx.Clone()
+		result, err := branch.rollbacks(s, ctx)
+		outcome := &Result{
+			Step:    idx,
+			Ctx:     ctx,
+			Attempt: branchResult.Attempt,
+			Result:  result,
+		}
+		if err != nil {
+			outcome.Error = err.Error()
+			failed = err
+		}
+		outcomes[branch.Name] = outcome
+	}
+
+	if len(outcomes) > 0 {
+		s.Rollbacks[idx] = &Result{
+			Step:     idx,
+			Ctx:      res.Ctx,
+			Attempt:  res.Attempt,
+			Branches: outcomes,
+		}
+	}
+
+	return failed
+}
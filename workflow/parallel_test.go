@@ -0,0 +1,156 @@
+package workflow
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testBranches
+* Registra las compensaciones de los branches en calls
+* @return func(name string) FnContext, func() []string
+**/
+func testBranches() (func(name string) FnContext, func() []string) {
+	mu := sync.Mutex{}
+	calls := make([]string, 0)
+	rollback := func(name string) FnContext {
+		return func(flow *Instance, ctx et.Json) (et.Json, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			calls = append(calls, name)
+			return ctx, nil
+		}
+	}
+
+	return rollback, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		result := slices.Clone(calls)
+		slices.Sort(result)
+		return result
+	}
+}
+
+/**
+* testFail
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func testFail(flow *Instance, ctx et.Json) (et.Json, error) {
+	return ctx, errors.New("boom")
+}
+
+func TestParallelWaitRollsBackDone(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	rollback, calls := testBranches()
+	wf.newFlowFn("parallel_wait", "v1", "Parallel", "", testPass, false, "test").
+		Parallel("Fan", "", 2, TpMergeNamespace, false).
+		BranchFn("A", "", testStep("a", 1), rollback("A")).
+		BranchFn("B", "", testFail, rollback("B")).
+		BranchFn("C", "", testFail, rollback("C"))
+
+	_, err := Run("parallel-1", "parallel_wait", 0, et.Json{}, et.Json{}, "test")
+	if err == nil {
+		t.Fatal("the step must fail when wait can not be reached")
+	}
+
+	instance := waitStatus(t, "parallel-1", FlowStatusFailed)
+	if got := calls(); !slices.Equal(got, []string{"A"}) {
+		t.Fatalf("only the completed branch must be compensated once, got %v", got)
+	}
+	if res := instance.Rollbacks[1]; res == nil || res.Branches["A"] == nil {
+		t.Fatal("the branch compensation must be stored")
+	}
+}
+
+func TestParallelWaitCompensatedOnFailure(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	rollback, calls := testBranches()
+	wf.newFlowFn("parallel_done", "v1", "Parallel", "", testPass, false, "test").
+		Parallel("Fan", "", 2, TpMergeNamespace, false).
+		BranchFn("A", "", testStep("a", 1), rollback("A")).
+		BranchFn("B", "", testStep("b", 2), rollback("B")).
+		BranchFn("C", "", testFail, rollback("C")).
+		StepFn("Charge", "", testFail, false)
+
+	_, err := Run("parallel-2", "parallel_done", 0, et.Json{}, et.Json{}, "test")
+	if err == nil {
+		t.Fatal("expected the charge error")
+	}
+
+	instance := waitStatus(t, "parallel-2", FlowStatusRolledBack)
+	if got := calls(); !slices.Equal(got, []string{"A", "B"}) {
+		t.Fatalf("the completed branches must be compensated, got %v", got)
+	}
+	ctx := instance.Ctxs[2]
+	if ctx == nil || ctx["A"] == nil || ctx["B"] == nil || ctx["C"] != nil {
+		t.Fatalf("wait 2 must merge the completed branches, got %v", ctx)
+	}
+}
+
+func TestRollbackBranchesSkipsCompensated(t *testing.T) {
+	rollback, calls := testBranches()
+	step := &Step{Name: "Fan", Type: TpParallel, Branches: []*Step{
+		{Name: "A", Type: TpFn, rollbacks: rollback("A")},
+		{Name: "B", Type: TpFn, rollbacks: rollback("B")},
+	}}
+	instance := &Instance{
+		Results: map[int]*Result{0: {Branches: map[string]*Result{
+			"A": {Ctx: et.Json{}},
+			"B": {Ctx: et.Json{}},
+		}}},
+		Rollbacks: map[int]*Result{0: {Branches: map[string]*Result{
+			"A": {Ctx: et.Json{}},
+			"B": {Ctx: et.Json{}, Error: "refund"},
+		}}},
+	}
+
+	err := instance.rollbackBranches(0, step)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if got := calls(); !slices.Equal(got, []string{"B"}) {
+		t.Fatalf("a compensated branch must not run again, got %v", got)
+	}
+	res := instance.Rollbacks[0]
+	if res.Branches["A"] == nil || res.Branches["B"].Error != "" {
+		t.Fatalf("the outcomes must be merged, got %v", res.Branches)
+	}
+}
+
+func TestParallelBranchTimeout(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	block := func(flow *Instance, ctx et.Json) (et.Json, error) {
+		<-release
+		return ctx, nil
+	}
+	flow := wf.newFlowFn("parallel_timeout", "v1", "Parallel", "", testPass, false, "test").
+		Parallel("Fan", "", 0, TpMergeAll, false).
+		BranchFn("Slow", "", block, nil).
+		BranchFn("Fast", "", testStep("fast", true), nil)
+	flow.Steps[1].Branches[0].Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := Run("parallel-3", "parallel_timeout", 0, et.Json{}, et.Json{}, "test")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the branch must fail at its deadline, took %v", elapsed)
+	}
+	if err == nil {
+		t.Fatal("expected the branch timeout")
+	}
+
+	instance := waitStatus(t, "parallel-3", FlowStatusFailed)
+	if res := instance.Results[1]; res == nil || res.Branches["Slow"] == nil || res.Branches["Slow"].Error == "" {
+		t.Fatal("the timed out branch must be recorded as failed")
+	}
+}
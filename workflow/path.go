@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* getPath
+* Obtiene el valor de una ruta separada por puntos, ej: invoice.lines.0.total
+* @param ctx et.Json, path string
+* @return interface{}, bool
+**/
+func getPath(ctx et.Json, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(path, "ctx.")
+	if path == "" || path == "ctx" {
+		return ctx, true
+	}
+
+	var current interface{} = ctx
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case et.Json:
+			val, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case map[string]interface{}:
+			val, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		default:
+			items, ok := toArray(current)
+			if !ok {
+				return nil, false
+			}
+
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(items) {
+				return nil, false
+			}
+			current = items[idx]
+		}
+	}
+
+	return current, true
+}
+
+/**
+* toArray
+* @param val interface{}
+* @return []interface{}, bool
+**/
+func toArray(val interface{}) ([]interface{}, bool) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, true
+	case []et.Json:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = item
+		}
+		return result, true
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = item
+		}
+		return result, true
+	case []string:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = item
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+/**
+* setPath
+* Asigna el valor en una ruta separada por puntos creando los objetos intermedios, retorna la llave raiz
+* @param ctx et.Json, path string, val interface{}
+* @return string
+**/
+func setPath(ctx et.Json, path string, val interface{}) string {
+	path = strings.TrimPrefix(path, "ctx.")
+	keys := strings.Split(path, ".")
+	current := ctx
+	for _, key := range keys[:len(keys)-1] {
+		switch v := current[key].(type) {
+		case et.Json:
+			current = v
+		case map[string]interface{}:
+			current = et.Json(v)
+		default:
+			next := et.Json{}
+			current[key] = next
+			current = next
+		}
+	}
+	current[keys[len(keys)-1]] = val
+
+	return keys[0]
+}
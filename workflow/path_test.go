@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestGetPath(t *testing.T) {
+	ctx := et.Json{
+		"invoice": et.Json{
+			"lines": []interface{}{
+				map[string]interface{}{"total": 10},
+				et.Json{"total": 20},
+			},
+			"tags": []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range []struct {
+		path string
+		want interface{}
+		ok   bool
+	}{
+		{"invoice.lines.0.total", 10, true},
+		{"ctx.invoice.lines.1.total", 20, true},
+		{"invoice.tags.1", "b", true},
+		{"invoice.lines.2.total", nil, false},
+		{"invoice.lines.x", nil, false},
+		{"invoice.missing", nil, false},
+		{"invoice.tags.0.name", nil, false},
+	} {
+		got, ok := getPath(ctx, tc.path)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Fatalf("%s: expected %v %v, got %v %v", tc.path, tc.want, tc.ok, got, ok)
+		}
+	}
+
+	if got, ok := getPath(ctx, "ctx"); !ok || got.(et.Json)["invoice"] == nil {
+		t.Fatal("ctx must return the whole context")
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	ctx := et.Json{
+		"customer": map[string]interface{}{"name": "ana"},
+		"total":    5,
+	}
+
+	if key := setPath(ctx, "ctx.customer.address.city", "bogota"); key != "customer" {
+		t.Fatalf("expected root key customer, got %s", key)
+	}
+	if got, ok := getPath(ctx, "customer.address.city"); !ok || got != "bogota" {
+		t.Fatalf("expected bogota, got %v", got)
+	}
+	if got, _ := getPath(ctx, "customer.name"); got != "ana" {
+		t.Fatalf("existing keys must be kept, got %v", got)
+	}
+
+	if key := setPath(ctx, "total.amount", 7); key != "total" {
+		t.Fatalf("expected root key total, got %s", key)
+	}
+	if got, ok := getPath(ctx, "total.amount"); !ok || got != 7 {
+		t.Fatalf("scalar must be replaced by an object, got %v", got)
+	}
+
+	if key := setPath(ctx, "status", "done"); key != "status" || ctx["status"] != "done" {
+		t.Fatalf("expected status done, got %v", ctx["status"])
+	}
+}
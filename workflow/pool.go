@@ -0,0 +1,317 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cgalvisleon/et/envar"
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/reg"
+)
+
+type TpJobStatus string
+
+const (
+	JobStatusQueued   TpJobStatus = "queued"
+	JobStatusRunning  TpJobStatus = "running"
+	JobStatusFinished TpJobStatus = "finished"
+)
+
+type job struct {
+	InstanceId string                     `json:"instance_id"`
+	Tag        string                     `json:"tag"`
+	Step       int                        `json:"step"`
+	Tags       et.Json                    `json:"tags"`
+	Ctx        et.Json                    `json:"ctx"`
+	RunBy      string                     `json:"run_by"`
+	Status     TpJobStatus                `json:"status"`
+	Result     et.Json                    `json:"result"`
+	err        error                      `json:"-"`
+	onDone     func(et.Json, error, bool) `json:"-"`
+	context    context.Context            `json:"-"`
+	done       chan struct{}              `json:"-"`
+}
+
+/**
+* ToJson
+* @return et.Json
+**/
+func (s *job) ToJson() et.Json {
+	errMessage := ""
+	if s.err != nil {
+		errMessage = s.err.Error()
+	}
+
+	return et.Json{
+		"instance_id": s.InstanceId,
+		"tag":         s.Tag,
+		"status":      s.Status,
+		"result":      s.Result,
+		"error":       errMessage,
+	}
+}
+
+type pool struct {
+	workFlows     *WorkFlows
+	limitRequests int
+	running       int
+	pending       []*job
+	jobs          map[string]*job
+	mu            sync.Mutex
+}
+
+/**
+* newPool
+* Limite de instancias en ejecucion simultanea, WORKFLOW_LIMIT_REQUESTS por defecto 10
+* @param workFlows *WorkFlows
+* @return *pool
+**/
+func newPool(workFlows *WorkFlows) *pool {
+	return &pool{
+		workFlows:     workFlows,
+		limitRequests: envar.GetInt("WORKFLOW_LIMIT_REQUESTS", 10),
+		pending:       make([]*job, 0),
+		jobs:          make(map[string]*job),
+		mu:            sync.Mutex{},
+	}
+}
+
+/**
+* setLimit
+* Al aumentar el limite se inician los pendientes que ya caben
+* @param limit int
+**/
+func (s *pool) setLimit(limit int) {
+	s.mu.Lock()
+	if limit < 1 {
+		limit = 1
+	}
+	s.limitRequests = limit
+	s.mu.Unlock()
+
+	s.next()
+}
+
+/**
+* submit
+* onDone se llama al terminar la ejecucion con false si la instancia no se pudo crear, puede ser nil
+* @param c context.Context, instanceId, tag string, step int, tags, ctx et.Json, runBy string, onDone func(et.Json, error, bool)
+* @return string, error
+**/
+func (s *pool) submit(c context.Context, instanceId, tag string, step int, tags, ctx et.Json, runBy string, onDone func(et.Json, error, bool)) (string, error) {
+	instanceId = reg.GetUUID(instanceId)
+
+	s.mu.Lock()
+	if current, ok := s.jobs[instanceId]; ok && current.Status != JobStatusFinished {
+		s.mu.Unlock()
+		return instanceId, fmt.Errorf(MSG_INSTANCE_ALREADY_RUNNING)
+	}
+
+	item := &job{
+		InstanceId: instanceId,
+		Tag:        tag,
+		Step:       step,
+		Tags:       tags,
+		Ctx:        ctx,
+		RunBy:      runBy,
+		Status:     JobStatusQueued,
+		onDone:     onDone,
+		context:    c,
+		done:       make(chan struct{}),
+	}
+	s.jobs[instanceId] = item
+	s.pending = append(s.pending, item)
+	if s.running >= s.limitRequests {
+		logs.Logf(packageName, MSG_WORKFLOW_LIMIT_REQUESTS, instanceId)
+	}
+	s.mu.Unlock()
+
+	s.next()
+	return instanceId, nil
+}
+
+/**
+* next
+* Inicia jobs pendientes mientras haya cupo
+**/
+func (s *pool) next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.running < s.limitRequests && len(s.pending) > 0 {
+		item := s.pending[0]
+		s.pending = s.pending[1:]
+		item.Status = JobStatusRunning
+		s.running++
+		logs.Logf(packageName, MSG_INSTANCE_INSTANCE_INC, s.running, s.limitRequests)
+		go s.execute(item)
+	}
+}
+
+/**
+* execute
+* @param item *job
+**/
+func (s *pool) execute(item *job) {
+	result := et.Json{}
+	instance, err := s.workFlows.getOrCreateInstance(item.InstanceId, item.Tag, item.Step, item.Tags, item.RunBy)
+	started := err == nil
+	if started {
+		result, err = s.workFlows.runInstance(item.context, instance, item.Step, item.Tags, item.Ctx, item.RunBy)
+	}
+	if item.onDone != nil {
+		item.onDone(result, err, started)
+	}
+
+	s.finish(item, result, err)
+	s.mu.Lock()
+	s.running--
+	logs.Logf(packageName, MSG_INSTANCE_INSTANCE_DEC, s.running, s.limitRequests)
+	s.mu.Unlock()
+
+	s.next()
+}
+
+/**
+* track
+* Registra una ejecucion sincrona para que poll y await la vean igual que las de submit
+* @param instanceId, tag string
+* @return *job, error
+**/
+func (s *pool) track(instanceId, tag string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.jobs[instanceId]; ok && current.Status != JobStatusFinished {
+		return nil, fmt.Errorf(MSG_INSTANCE_ALREADY_RUNNING)
+	}
+
+	result := &job{
+		InstanceId: instanceId,
+		Tag:        tag,
+		Status:     JobStatusRunning,
+		done:       make(chan struct{}),
+	}
+	s.jobs[instanceId] = result
+
+	return result, nil
+}
+
+/**
+* finish
+* El job se conserva durante el RetentionTime del flujo
+* @param item *job, result et.Json, err error
+**/
+func (s *pool) finish(item *job, result et.Json, err error) {
+	s.mu.Lock()
+	item.Result = result
+	item.err = err
+	item.Status = JobStatusFinished
+	close(item.done)
+	retention := 15 * time.Minute
+	if flow := s.workFlows.getFlowByTag(item.Tag); flow != nil && flow.RetentionTime > 0 {
+		retention = flow.RetentionTime
+	}
+	s.mu.Unlock()
+
+	time.AfterFunc(retention, func() {
+		s.remove(item)
+	})
+}
+
+/**
+* remove
+* @param item *job
+**/
+func (s *pool) remove(item *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.jobs[item.InstanceId] == item {
+		delete(s.jobs, item.InstanceId)
+	}
+}
+
+/**
+* get
+* @param instanceId string
+* @return *job, bool
+**/
+func (s *pool) get(instanceId string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.jobs[instanceId]
+	return result, ok
+}
+
+/**
+* jobStatus
+* Estado de una ejecucion sin job en este worker segun el status de la instancia
+* @param status FlowStatus
+* @return TpJobStatus
+**/
+func jobStatus(status FlowStatus) TpJobStatus {
+	switch status {
+	case FlowStatusPending, FlowStatusRunning, FlowStatusRollingBack:
+		return JobStatusRunning
+	default:
+		return JobStatusFinished
+	}
+}
+
+/**
+* poll
+* Estado del job y de la instancia guardada, la de memoria puede estar en ejecucion.
+* Sin job el estado se toma del status de la instancia
+* @param instanceId string
+* @return et.Json, error
+**/
+func (s *pool) poll(instanceId string) (et.Json, error) {
+	item, ok := s.get(instanceId)
+	if !ok {
+		instance, err := getStore().GetInstance(instanceId)
+		if err != nil {
+			return et.Json{}, errorInstanceNotFound
+		}
+
+		return et.Json{
+			"instance_id": instance.Id,
+			"tag":         instance.Tag,
+			"status":      jobStatus(instance.Status),
+			"instance":    instance.ToJson(),
+		}, nil
+	}
+
+	s.mu.Lock()
+	result := item.ToJson()
+	s.mu.Unlock()
+	if instance, err := getStore().GetInstance(instanceId); err == nil {
+		result["instance"] = instance.ToJson()
+	}
+
+	return result, nil
+}
+
+/**
+* await
+* Espera a que el job termine o a que c se cancele
+* @param c context.Context, instanceId string
+* @return et.Json, error
+**/
+func (s *pool) await(c context.Context, instanceId string) (et.Json, error) {
+	item, ok := s.get(instanceId)
+	if !ok {
+		return s.poll(instanceId)
+	}
+
+	select {
+	case <-item.done:
+		return item.Result, item.err
+	case <-c.Done():
+		return et.Json{}, c.Err()
+	}
+}
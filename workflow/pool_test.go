@@ -0,0 +1,121 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testJobStatus
+* @param t *testing.T, instanceId string
+* @return TpJobStatus
+**/
+func testJobStatus(t *testing.T, instanceId string) TpJobStatus {
+	t.Helper()
+
+	result, err := Poll(instanceId)
+	if err != nil {
+		t.Fatalf("poll %s: %v", instanceId, err)
+	}
+
+	status, _ := result["status"].(TpJobStatus)
+	return status
+}
+
+func TestPoolLimitQueues(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	wf.newFlowFn("pool", "v1", "Pool", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		n := running.Add(1)
+		for {
+			current := peak.Load()
+			if n <= current || peak.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return et.Json{"ok": true}, nil
+	}, false, "test")
+	if err := SetLimitRequests(1); err != nil {
+		t.Fatalf("limit: %v", err)
+	}
+
+	ids := make([]string, 3)
+	for i := range ids {
+		id, err := RunAsync(fmt.Sprintf("pool-%d", i), "pool", 0, et.Json{}, et.Json{}, "test")
+		if err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+		ids[i] = id
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for running.Load() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the first job must start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := testJobStatus(t, ids[2]); status != JobStatusQueued {
+		t.Fatalf("a job over the limit must be queued, got %s", status)
+	}
+
+	// Al subir el limite se inicia un pendiente mas
+	if err := SetLimitRequests(2); err != nil {
+		t.Fatalf("limit: %v", err)
+	}
+	for running.Load() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("raising the limit must start a queued job")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	for _, id := range ids {
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		result, err := Await(c, id)
+		cancel()
+		if err != nil || !result.Bool("ok") {
+			t.Fatalf("await %s: %v %v", id, result, err)
+		}
+		if status := testJobStatus(t, id); status != JobStatusFinished {
+			t.Fatalf("expected finished, got %s", status)
+		}
+	}
+	if n := peak.Load(); n > 2 {
+		t.Fatalf("no more than the limit may run at once, got %d", n)
+	}
+}
+
+func TestPoolRejectsRunningId(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	release := make(chan struct{})
+	wf.newFlowFn("pool_dup", "v1", "Pool", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		<-release
+		return ctx, nil
+	}, false, "test")
+
+	id, err := RunAsync("pool-dup", "pool_dup", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	_, err = RunAsync(id, "pool_dup", 0, et.Json{}, et.Json{}, "test")
+	if err == nil || err.Error() != MSG_INSTANCE_ALREADY_RUNNING {
+		t.Fatalf("a running id must be rejected, got %v", err)
+	}
+
+	close(release)
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := Await(c, id); err != nil {
+		t.Fatalf("await: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/reg"
+	"github.com/cgalvisleon/et/timezone"
+	"github.com/cgalvisleon/workflow/vm"
+)
+
+type ReplayOptions struct {
+	Clone      bool    `json:"clone"`
+	InstanceId string  `json:"instance_id"`
+	Ctx        et.Json `json:"ctx"`
+	ReplayBy   string  `json:"replay_by"`
+}
+
+type Execution struct {
+	Step  int     `json:"step"`
+	Ctx   et.Json `json:"ctx"`
+	Input et.Json `json:"input"`
+}
+
+/**
+* trace
+* Registra cada ejecucion de un step en orden, con el ctx de la instancia antes de iniciar y
+* el ctx de entrada, el replay parte de la ultima ejecucion del step
+* @param input et.Json
+**/
+func (s *Instance) trace(input et.Json) {
+	s.Executions = append(s.Executions, &Execution{
+		Step:  s.Current,
+		Ctx:   s.Ctx.Clone(),
+		Input: input.Clone(),
+	})
+}
+
+/**
+* lastExecution
+* @param step int
+* @return int, -1 si el step no se ha ejecutado
+**/
+func (s *Instance) lastExecution(step int) int {
+	for i := len(s.Executions) - 1; i >= 0; i-- {
+		if s.Executions[i].Step == step {
+			return i
+		}
+	}
+
+	return -1
+}
+
+/**
+* prefix
+* Estado de la instancia antes de la ejecucion n: steps completados en orden, ctx de entrada
+* de cada step y steps que se ejecutaron desde n
+* @param n int
+* @return []int, map[int]et.Json, map[int]bool
+**/
+func (s *Instance) prefix(n int) ([]int, map[int]et.Json, map[int]bool) {
+	inputs := make(map[int]et.Json)
+	order := make([]int, 0)
+	for _, execution := range s.Executions[:n] {
+		inputs[execution.Step] = execution.Input
+		order = slices.DeleteFunc(order, func(idx int) bool { return idx == execution.Step })
+		order = append(order, execution.Step)
+	}
+
+	after := make(map[int]bool)
+	for _, execution := range s.Executions[n:] {
+		after[execution.Step] = true
+	}
+
+	completed := make([]int, 0, len(order))
+	for _, idx := range order {
+		if slices.Contains(s.Completed, idx) {
+			completed = append(completed, idx)
+		}
+	}
+
+	return completed, inputs, after
+}
+
+/**
+* discard
+* Elimina lo ocurrido desde la ejecucion n: resultados, snapshots, compensaciones e hijos de los
+* steps que se ejecutaron despues, en orden de ejecucion para que los ciclos se deshagan bien
+* @param n int
+**/
+func (s *Instance) discard(n int) {
+	completed, inputs, after := s.prefix(n)
+	s.Completed = completed
+	s.Executions = s.Executions[:n]
+
+	for idx := range after {
+		delete(s.Results, idx)
+		delete(s.Rollbacks, idx)
+		if input, ok := inputs[idx]; ok {
+			s.Ctxs[idx] = input.Clone()
+		} else {
+			delete(s.Ctxs, idx)
+		}
+	}
+
+	children := make([]string, 0, len(s.Children))
+	for _, id := range s.Children {
+		suffix, ok := strings.CutPrefix(id, s.Id+":")
+		idx, err := strconv.Atoi(suffix)
+		if ok && err == nil && after[idx] {
+			if s.workFlows != nil {
+				s.workFlows.delete(id)
+			}
+			continue
+		}
+
+		children = append(children, id)
+	}
+	s.Children = children
+
+	if s.workFlows != nil {
+		s.workFlows.timers.cancel(s.Id)
+	}
+	s.WaitingSignal = ""
+	s.SignalDeadline = time.Time{}
+	s.WakeAt = time.Time{}
+}
+
+/**
+* clone
+* Copia de la instancia con lo ocurrido antes de la ejecucion n, sin hijos ni señales pendientes
+* @param id string, n int, createdBy string
+* @return *Instance
+**/
+func (s *Instance) clone(id string, n int, createdBy string) *Instance {
+	now := timezone.NowTime()
+	completed, inputs, after := s.prefix(n)
+	result := &Instance{
+		Flow:       s.Flow,
+		workFlows:  s.workFlows,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Tag:        s.Tag,
+		Id:         id,
+		CreatedBy:  createdBy,
+		UpdatedBy:  createdBy,
+		Current:    s.Executions[n].Step,
+		Ctx:        et.Json{},
+		Ctxs:       make(map[int]et.Json),
+		PinnedData: s.PinnedData.Clone(),
+		Results:    make(map[int]*Result),
+		Rollbacks:  make(map[int]*Result),
+		Signals:    make(map[string]et.Json),
+		Tags:       s.Tags.Clone(),
+		WorkerHost: workerHost,
+		ReplayOf:   s.Id,
+		Completed:  completed,
+		goTo:       -1,
+		vm:         vm.New(),
+	}
+
+	for _, execution := range s.Executions[:n] {
+		result.Executions = append(result.Executions, &Execution{
+			Step:  execution.Step,
+			Ctx:   execution.Ctx.Clone(),
+			Input: execution.Input.Clone(),
+		})
+	}
+
+	for idx, input := range inputs {
+		result.Ctxs[idx] = input.Clone()
+	}
+
+	for idx, res := range s.Results {
+		if after[idx] {
+			continue
+		}
+
+		copied := *res
+		copied.Branches = maps.Clone(res.Branches)
+		copied.Attempts = slices.Clone(res.Attempts)
+		result.Results[idx] = &copied
+	}
+
+	return result
+}
+
+/**
+* replay
+* Vuelve a ejecutar desde la ultima ejecucion de fromStep con el ctx con el que inicio,
+* sin repetir los steps anteriores. El bloqueo se mantiene durante todo el replay
+* @param instanceId string, fromStep int, opts ReplayOptions
+* @return et.Json, error
+**/
+func (s *WorkFlows) replay(instanceId string, fromStep int, opts ReplayOptions) (et.Json, error) {
+	instance, exists := s.loadInstance(instanceId)
+	if !exists {
+		return et.Json{}, errorInstanceNotFound
+	}
+
+	flow := s.getFlowByTag(instance.Tag)
+	if flow == nil {
+		return et.Json{}, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+
+	if fromStep < 0 || fromStep >= len(flow.Steps) {
+		return et.Json{}, fmt.Errorf(MSG_VALIDATE_GOTO_OUT_OF_RANGE, fromStep, len(flow.Steps))
+	}
+
+	unlock, err := s.acquire(instance)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	n := instance.lastExecution(fromStep)
+	if n == -1 {
+		unlock()
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_REPLAY_NOT_RECORDED, fromStep, instanceId)
+	}
+
+	execution := instance.Executions[n]
+	snapshot := execution.Ctx.Clone()
+	input := execution.Input.Clone()
+	for k, v := range opts.Ctx {
+		input[k] = v
+	}
+
+	replayBy := opts.ReplayBy
+	if replayBy == "" {
+		replayBy = instance.UpdatedBy
+	}
+
+	target := instance
+	if opts.Clone {
+		target = instance.clone(reg.GetUUID(opts.InstanceId), n, replayBy)
+		unlock()
+		current, exists := s.addOrGet(target)
+		if exists {
+			return et.Json{}, fmt.Errorf(MSG_INSTANCE_ALREADY_EXISTS, current.Id)
+		}
+
+		unlock, err = s.acquire(target)
+		if err != nil {
+			return et.Json{}, err
+		}
+	} else {
+		target.discard(n)
+	}
+
+	result, err := s.replayLocked(target, flow, fromStep, snapshot, input, replayBy, instanceId)
+	unlock()
+	s.deliver(target)
+
+	return result, err
+}
+
+/**
+* replayLocked
+* @param target *Instance, flow *Flow, fromStep int, snapshot, input et.Json, replayBy, instanceId string
+* @return et.Json, error
+**/
+func (s *WorkFlows) replayLocked(target *Instance, flow *Flow, fromStep int, snapshot, input et.Json, replayBy, instanceId string) (et.Json, error) {
+	target.Flow = flow
+	target.Ctx = snapshot
+	target.Current = fromStep
+	target.DoneAt = time.Time{}
+	target.done = false
+	target.err = nil
+	target.resilence = nil
+	target.UpdatedBy = replayBy
+	target.addHistory(HistoryReplay, fmt.Sprintf(MSG_INSTANCE_REPLAY_HISTORY, instanceId, fromStep))
+	err := target.SetStatus(FlowStatusPending)
+	if err != nil {
+		return et.Json{}, err
+	}
+	logs.Logf(packageName, MSG_INSTANCE_REPLAY, target.Id, instanceId, fromStep)
+
+	return s.runLocked(context.Background(), target, fromStep, et.Json{}, input, replayBy)
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testReplayFlow
+* Cuenta las ejecuciones de cada step
+* @param wf *WorkFlows, starts, charges *atomic.Int32
+* @return *Flow
+**/
+func testReplayFlow(wf *WorkFlows, starts, charges *atomic.Int32) *Flow {
+	return wf.newFlowFn("replay", "v1", "Replay", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		starts.Add(1)
+		return et.Json{"started": true}, nil
+	}, false, "test").
+		StepFn("Charge", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+			charges.Add(1)
+			return et.Json{"charged": ctx.Int("amount")}, nil
+		}, false).
+		StepFn("End", "", testStep("end", true), false)
+}
+
+func TestReplayInPlace(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	var starts, charges atomic.Int32
+	testReplayFlow(wf, &starts, &charges)
+	testVisited(t, "replay-1", "replay", et.Json{"amount": 10})
+
+	_, err := Replay("replay-1", 1, ReplayOptions{Ctx: et.Json{"amount": 50}})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	result := waitStatus(t, "replay-1", FlowStatusDone)
+	if starts.Load() != 1 || charges.Load() != 2 {
+		t.Fatalf("only the steps from the replayed one must run again, starts %d charges %d", starts.Load(), charges.Load())
+	}
+	if result.Ctx.Int("charged") != 50 || !result.Ctx.Bool("end") {
+		t.Fatalf("the replay must use the new input, ctx %v", result.Ctx)
+	}
+}
+
+func TestReplayClone(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	var starts, charges atomic.Int32
+	testReplayFlow(wf, &starts, &charges)
+	testVisited(t, "replay-2", "replay", et.Json{"amount": 10})
+
+	_, err := Replay("replay-2", 1, ReplayOptions{Clone: true, InstanceId: "replay-2-clone", Ctx: et.Json{"amount": 70}})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	clone := waitStatus(t, "replay-2-clone", FlowStatusDone)
+	if clone.ReplayOf != "replay-2" || clone.Ctx.Int("charged") != 70 {
+		t.Fatalf("the clone must run from Charge with the new input, got %s %v", clone.ReplayOf, clone.Ctx)
+	}
+	if starts.Load() != 1 || charges.Load() != 2 {
+		t.Fatalf("the clone must not repeat Start, starts %d charges %d", starts.Load(), charges.Load())
+	}
+
+	original := waitStatus(t, "replay-2", FlowStatusDone)
+	if original.Ctx.Int("charged") != 10 {
+		t.Fatalf("the original instance must not change, ctx %v", original.Ctx)
+	}
+
+	_, err = Replay("replay-2", 1, ReplayOptions{Clone: true, InstanceId: "replay-2-clone"})
+	if err == nil {
+		t.Fatal("a clone id that already exists must be rejected")
+	}
+}
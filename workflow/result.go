@@ -7,11 +7,13 @@ import (
 )
 
 type Result struct {
-	Step    int     `json:"step"`
-	Ctx     et.Json `json:"ctx"`
-	Attempt int     `json:"attempt"`
-	Result  et.Json `json:"result"`
-	Error   string  `json:"error"`
+	Step     int                `json:"step"`
+	Ctx      et.Json            `json:"ctx"`
+	Attempt  int                `json:"attempt"`
+	Result   et.Json            `json:"result"`
+	Error    string             `json:"error"`
+	Branches map[string]*Result `json:"branches"`
+	Attempts []*Result          `json:"attempts"`
 }
 
 /**
@@ -33,11 +35,13 @@ func (s *Result) Serialize() (string, error) {
 **/
 func (s *Result) ToJson() et.Json {
 	return et.Json{
-		"step":    s.Step,
-		"ctx":     s.Ctx,
-		"attempt": s.Attempt,
-		"result":  s.Result,
-		"error":   s.Error,
+		"step":     s.Step,
+		"ctx":      s.Ctx,
+		"attempt":  s.Attempt,
+		"result":   s.Result,
+		"error":    s.Error,
+		"branches": s.Branches,
+		"attempts": s.Attempts,
 	}
 }
 
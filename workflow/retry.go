@@ -0,0 +1,140 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+)
+
+type Retry struct {
+	MaxAttempts int              `json:"max_attempts"`
+	Delay       time.Duration    `json:"delay"`
+	Multiplier  float64          `json:"multiplier"`
+	MaxDelay    time.Duration    `json:"max_delay"`
+	Jitter      float64          `json:"jitter"`
+	Codes       []string         `json:"codes"`
+	retryable   func(error) bool `json:"-"`
+}
+
+/**
+* retryableErr
+* Sin predicado ni codigos todos los errores se reintentan
+* @param err error
+* @return bool
+**/
+func (s *Retry) retryableErr(err error) bool {
+	if s.retryable != nil {
+		return s.retryable(err)
+	}
+
+	if len(s.Codes) == 0 {
+		return true
+	}
+
+	return slices.Contains(s.Codes, errorCode(err))
+}
+
+/**
+* delay
+* Delay * Multiplier^(attempt-1), limitado por MaxDelay y con jitter como fraccion del delay
+* @param attempt int
+* @return time.Duration
+**/
+func (s *Retry) delay(attempt int) time.Duration {
+	multiplier := s.Multiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	result := float64(s.Delay) * math.Pow(multiplier, float64(attempt-1))
+	if s.MaxDelay > 0 && result > float64(s.MaxDelay) {
+		result = float64(s.MaxDelay)
+	}
+
+	if s.Jitter > 0 {
+		result += result * s.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	if result < 0 {
+		return 0
+	}
+
+	return time.Duration(result)
+}
+
+/**
+* addAttempt
+* Guarda el intento fallido en el resultado del step actual
+* @param attempt int, result et.Json, err error
+**/
+func (s *Instance) addAttempt(attempt int, result et.Json, err error) {
+	res := s.Results[s.Current]
+	if res == nil {
+		res = &Result{Step: s.Current}
+		s.Results[s.Current] = res
+	}
+
+	res.Attempts = append(res.Attempts, &Result{
+		Step:    s.Current,
+		Ctx:     s.Ctx.Clone(),
+		Attempt: attempt,
+		Result:  result,
+		Error:   err.Error(),
+	})
+}
+
+/**
+* runStep
+* Ejecuta el step aplicando su politica de reintentos antes de fallar hacia el rollback
+* @param step *Step, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Instance) runStep(step *Step, ctx et.Json) (et.Json, error) {
+	attempt := 1
+	for {
+		result, err := step.run(s, ctx)
+		if err == nil || step.Retry == nil || s.Context().Err() != nil {
+			return result, err
+		}
+
+		if attempt >= step.Retry.MaxAttempts || !step.Retry.retryableErr(err) {
+			return result, err
+		}
+
+		s.addAttempt(attempt, result, err)
+		s.addHistory(HistoryStepRetry, fmt.Sprintf(MSG_INSTANCE_STEP_RETRY_HISTORY, attempt, err.Error()))
+		delay := step.Retry.delay(attempt)
+		logs.Logf(packageName, MSG_INSTANCE_STEP_RETRY, step.Name, attempt, delay, err.Error())
+		if !s.wait(delay) {
+			return result, err
+		}
+
+		attempt++
+	}
+}
+
+/**
+* wait
+* @param delay time.Duration
+* @return bool, false si el contexto de la instancia termino
+**/
+func (s *Instance) wait(delay time.Duration) bool {
+	if delay <= 0 {
+		return s.Context().Err() == nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	retry := &Retry{
+		Delay:      100 * time.Millisecond,
+		Multiplier: 2,
+		MaxDelay:   time.Second,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, want := range expected {
+		if got := retry.delay(i + 1); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestRetryDelayFixed(t *testing.T) {
+	retry := &Retry{Delay: 50 * time.Millisecond}
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := retry.delay(attempt); got != 50*time.Millisecond {
+			t.Fatalf("attempt %d: expected a fixed delay, got %s", attempt, got)
+		}
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	retry := &Retry{
+		Delay:  time.Second,
+		Jitter: 0.2,
+	}
+
+	for i := 0; i < 100; i++ {
+		got := retry.delay(1)
+		if got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("delay out of the jitter range: %s", got)
+		}
+	}
+}
+
+func TestRetryableErr(t *testing.T) {
+	all := &Retry{}
+	if !all.retryableErr(errors.New("boom")) {
+		t.Fatal("without codes every error is retryable")
+	}
+
+	codes := &Retry{Codes: []string{"unavailable"}}
+	if !codes.retryableErr(NewError("unavailable", "service down")) {
+		t.Fatal("listed code must be retryable")
+	}
+	if codes.retryableErr(NewError("invalid", "bad input")) {
+		t.Fatal("unlisted code must not be retryable")
+	}
+	if codes.retryableErr(errors.New("boom")) {
+		t.Fatal("error without code must not be retryable when codes are set")
+	}
+
+	predicate := &Retry{
+		Codes: []string{"unavailable"},
+		retryable: func(err error) bool {
+			return err.Error() == "boom"
+		},
+	}
+	if !predicate.retryableErr(errors.New("boom")) || predicate.retryableErr(NewError("unavailable", "down")) {
+		t.Fatal("predicate must take precedence over codes")
+	}
+}
@@ -0,0 +1,153 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+)
+
+/**
+* setCompleted
+* Registra el step como terminado, si ya estaba se mueve al final para compensar en orden inverso
+* @param step int
+**/
+func (s *Instance) setCompleted(step int) {
+	idx := slices.Index(s.Completed, step)
+	if idx != -1 {
+		s.Completed = slices.Delete(s.Completed, idx, idx+1)
+	}
+
+	s.Completed = append(s.Completed, step)
+}
+
+/**
+* attempt
+* @return int
+**/
+func (s *Instance) attempt() int {
+	if s.resilence == nil {
+		return 0
+	}
+
+	return s.resilence.Attempt
+}
+
+/**
+* compensateStep
+* Ejecuta la compensacion del step y guarda el resultado en Rollbacks, sea exitoso o no
+* @param idx int, step *Step
+* @return error
+**/
+func (s *Instance) compensateStep(idx int, step *Step) error {
+	var err error
+	if step.Type == TpParallel {
+		err = s.rollbackBranches(idx, step)
+	}
+
+	if step.rollbacks == nil {
+		return err
+	}
+
+	ctx := et.Json{}
+	if s.Ctxs[idx] != nil {
+		ctx = s.Ctxs[idx].Clone()
+	}
+
+	logs.Logf(packageName, MSG_INSTANCE_ROLLBACK_STEP, idx)
+	result, stepErr := step.rollbacks(s, ctx)
+	res := &Result{
+		Step:    idx,
+		Ctx:     ctx,
+		Attempt: s.attempt(),
+		Result:  result,
+	}
+	if prev := s.Rollbacks[idx]; prev != nil {
+		res.Branches = prev.Branches
+	}
+	if stepErr != nil {
+		res.Error = stepErr.Error()
+		err = stepErr
+	}
+	s.Rollbacks[idx] = res
+	s.addHistory(HistoryRollback, fmt.Sprintf(MSG_INSTANCE_ROLLBACK_HISTORY, step.Name, res.Error))
+
+	return err
+}
+
+/**
+* compensable
+* Si algun step terminado tiene compensacion, propia o de sus branches
+* @return bool
+**/
+func (s *Instance) compensable() bool {
+	for _, idx := range s.Completed {
+		if idx < 0 || idx >= len(s.Steps) || s.Steps[idx] == nil {
+			continue
+		}
+
+		step := s.Steps[idx]
+		if step.rollbacks != nil {
+			return true
+		}
+
+		for _, branch := range step.Branches {
+			if branch.rollbacks != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/**
+* compensate
+* Compensa en orden inverso los steps terminados. El avance se guarda despues de cada step,
+* si el proceso se cae la compensacion continua desde los steps pendientes. Un step solo sale de
+* Completed cuando su compensacion termina bien, sin nada que compensar el estado queda failed
+* @return et.Json, error
+**/
+func (s *Instance) compensate() (et.Json, error) {
+	if s.Status != FlowStatusRollingBack {
+		if !s.compensable() {
+			return s.Ctx, nil
+		}
+
+		s.SetStatus(FlowStatusRollingBack)
+	}
+
+	var failed error
+	pending := s.Completed
+	kept := make([]int, 0)
+	for i := len(pending) - 1; i >= 0; i-- {
+		idx := pending[i]
+		if idx >= 0 && idx < len(s.Steps) && s.Steps[idx] != nil {
+			err := s.compensateStep(idx, s.Steps[idx])
+			if err != nil {
+				failed = err
+				if s.TpConsistency == TpConsistencyStrong {
+					s.SetStatus(FlowStatusCompensationFailed)
+					return s.Ctx, fmt.Errorf(MSG_INSTANCE_COMPENSATION_FAILED, s.Id, idx, err.Error())
+				}
+
+				kept = append([]int{idx}, kept...)
+			}
+		}
+
+		s.Completed = append(slices.Clone(pending[:i]), kept...)
+		s.Save()
+		if aborted := s.abortErr(); aborted != nil {
+			return s.Ctx, aborted
+		}
+	}
+
+	if failed != nil {
+		s.SetStatus(FlowStatusCompensationFailed)
+		return s.Ctx, fmt.Errorf(MSG_INSTANCE_COMPENSATION_FAILED, s.Id, s.Current, failed.Error())
+	}
+
+	s.SetStatus(FlowStatusRolledBack)
+	return s.Ctx, nil
+}
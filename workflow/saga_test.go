@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+const (
+	sagaOk   = "ok"
+	sagaFail = "fail"
+	sagaNone = "none"
+)
+
+/**
+* testSaga
+* Instancia con un step terminado por cada kind: compensacion exitosa, fallida o sin compensacion
+* @param t *testing.T, consistency TpConsistency, calls *[]int, kinds ...string
+* @return *Instance
+**/
+func testSaga(t *testing.T, consistency TpConsistency, calls *[]int, kinds ...string) *Instance {
+	t.Helper()
+
+	SetStore(NewMemoryStore())
+	t.Cleanup(func() { SetStore(nil) })
+
+	flow := newFlow("saga_test", "1.0.0", "Saga", "Saga", "test")
+	flow.TpConsistency = consistency
+	result := &Instance{
+		Flow:      flow,
+		Id:        "saga",
+		Tag:       flow.Tag,
+		Status:    FlowStatusFailed,
+		Ctx:       et.Json{},
+		Ctxs:      make(map[int]et.Json),
+		Results:   make(map[int]*Result),
+		Rollbacks: make(map[int]*Result),
+	}
+
+	for i, kind := range kinds {
+		step := &Step{Name: fmt.Sprintf("step_%d", i), Type: TpFn}
+		if kind != sagaNone {
+			idx, fail := i, kind == sagaFail
+			step.rollbacks = func(flow *Instance, ctx et.Json) (et.Json, error) {
+				*calls = append(*calls, idx)
+				if fail {
+					return ctx, errors.New("refund")
+				}
+
+				return ctx, nil
+			}
+		}
+		flow.Steps = append(flow.Steps, step)
+		result.setCompleted(i)
+	}
+
+	return result
+}
+
+func TestSetCompleted(t *testing.T) {
+	instance := &Instance{}
+	for _, step := range []int{0, 1, 2, 1, 3} {
+		instance.setCompleted(step)
+	}
+
+	if !slices.Equal(instance.Completed, []int{0, 2, 1, 3}) {
+		t.Fatalf("repeated step must move to the end, got %v", instance.Completed)
+	}
+}
+
+func TestCompensateOrder(t *testing.T) {
+	calls := make([]int, 0)
+	instance := testSaga(t, TpConsistencyEventual, &calls, sagaOk, sagaNone, sagaOk)
+
+	if _, err := instance.compensate(); err != nil {
+		t.Fatalf("compensate: %v", err)
+	}
+	if !slices.Equal(calls, []int{2, 0}) {
+		t.Fatalf("expected reverse order [2 0], got %v", calls)
+	}
+	if instance.Status != FlowStatusRolledBack || len(instance.Completed) != 0 {
+		t.Fatalf("expected rolled back with nothing pending, got %s %v", instance.Status, instance.Completed)
+	}
+
+	stored, err := getStore().GetInstance("saga")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Status != FlowStatusRolledBack {
+		t.Fatalf("progress must be saved, got %s", stored.Status)
+	}
+}
+
+func TestCompensateEventualKeepsFailed(t *testing.T) {
+	calls := make([]int, 0)
+	instance := testSaga(t, TpConsistencyEventual, &calls, sagaOk, sagaNone, sagaFail, sagaOk)
+
+	if _, err := instance.compensate(); err == nil {
+		t.Fatal("failed compensation must return an error")
+	}
+	if !slices.Equal(calls, []int{3, 2, 0}) {
+		t.Fatalf("eventual mode must continue after a failure, got %v", calls)
+	}
+	if instance.Status != FlowStatusCompensationFailed {
+		t.Fatalf("expected compensation failed, got %s", instance.Status)
+	}
+	if !slices.Equal(instance.Completed, []int{2}) {
+		t.Fatalf("only the failed step must stay pending, got %v", instance.Completed)
+	}
+	if instance.Rollbacks[2] == nil || instance.Rollbacks[2].Error != "refund" {
+		t.Fatal("failed compensation must be recorded")
+	}
+}
+
+func TestCompensateStrongStops(t *testing.T) {
+	calls := make([]int, 0)
+	instance := testSaga(t, TpConsistencyStrong, &calls, sagaOk, sagaFail, sagaOk)
+
+	if _, err := instance.compensate(); err == nil {
+		t.Fatal("failed compensation must return an error")
+	}
+	if !slices.Equal(calls, []int{2, 1}) {
+		t.Fatalf("strong mode must stop at the failure, got %v", calls)
+	}
+	if !slices.Equal(instance.Completed, []int{0, 1}) {
+		t.Fatalf("failed and earlier steps must stay pending, got %v", instance.Completed)
+	}
+}
+
+func TestCompensateNothing(t *testing.T) {
+	calls := make([]int, 0)
+	instance := testSaga(t, TpConsistencyEventual, &calls, sagaNone, sagaNone)
+
+	if _, err := instance.compensate(); err != nil {
+		t.Fatalf("compensate: %v", err)
+	}
+	if instance.Status != FlowStatusFailed {
+		t.Fatalf("without compensations the status must not change, got %s", instance.Status)
+	}
+}
+
+func TestRollbackDefinitionRuns(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("rollback_js", "v1", "Rollback", "", testStep("reserved", true), false, "test").
+		RollbackDefinition("result = { refunded: ctx.amount }").
+		StepFn("Charge", "", testFail, false)
+
+	_, err := Run("rollback-js", "rollback_js", 0, et.Json{}, et.Json{"amount": 30}, "test")
+	if err == nil {
+		t.Fatal("expected the charge error")
+	}
+
+	instance := waitStatus(t, "rollback-js", FlowStatusRolledBack)
+	res := instance.Rollbacks[0]
+	if res == nil || res.Error != "" || res.Result.Int("refunded") != 30 {
+		t.Fatalf("the javascript compensation must run with the step ctx, got %v", res)
+	}
+}
@@ -0,0 +1,123 @@
+package workflow
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/timezone"
+)
+
+const (
+	timersRetryDelay = time.Minute
+	timersBusyDelay  = 100 * time.Millisecond
+)
+
+type scheduler struct {
+	workFlows *WorkFlows
+	timers    map[string]*time.Timer
+	mu        sync.Mutex
+}
+
+/**
+* newScheduler
+* @param workFlows *WorkFlows
+* @return *scheduler
+**/
+func newScheduler(workFlows *WorkFlows) *scheduler {
+	return &scheduler{
+		workFlows: workFlows,
+		timers:    make(map[string]*time.Timer),
+		mu:        sync.Mutex{},
+	}
+}
+
+/**
+* schedule
+* Programa el despertar de la instancia, el momento se guarda con la instancia (WakeAt o SignalDeadline)
+* y al reiniciar los timers se reconstruyen desde el store
+* @param instanceId string, wakeAt time.Time
+**/
+func (s *scheduler) schedule(instanceId string, wakeAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.timers[instanceId]; ok {
+		timer.Stop()
+	}
+
+	delay := time.Until(wakeAt)
+	if delay < 0 {
+		delay = 0
+	}
+
+	s.timers[instanceId] = time.AfterFunc(delay, func() {
+		s.fire(instanceId)
+	})
+}
+
+/**
+* cancel
+* @param instanceId string
+**/
+func (s *scheduler) cancel(instanceId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, ok := s.timers[instanceId]
+	if ok {
+		timer.Stop()
+		delete(s.timers, instanceId)
+	}
+}
+
+/**
+* fire
+* Si la instancia aun no se puede cargar porque su flujo no esta registrado se reintenta mas tarde
+* @param instanceId string
+**/
+func (s *scheduler) fire(instanceId string) {
+	s.mu.Lock()
+	delete(s.timers, instanceId)
+	s.mu.Unlock()
+
+	_, exists := s.workFlows.loadInstance(instanceId)
+	if !exists {
+		instance, err := getStore().GetInstance(instanceId)
+		if err == nil && instance != nil {
+			s.schedule(instanceId, timezone.NowTime().Add(timersRetryDelay))
+		}
+		return
+	}
+
+	err := s.workFlows.wake(instanceId)
+	if err != nil {
+		logs.Error(err)
+	}
+}
+
+/**
+* restore
+* Reconstruye los timers de las instancias en espera desde su WakeAt o SignalDeadline
+* @param instances []*Instance
+**/
+func (s *scheduler) restore(instances []*Instance) {
+	result := 0
+	for _, instance := range instances {
+		if instance.Status != FlowStatusWaiting {
+			continue
+		}
+
+		if !instance.WakeAt.IsZero() {
+			s.schedule(instance.Id, instance.WakeAt)
+			result++
+		} else if !instance.SignalDeadline.IsZero() {
+			s.schedule(instance.Id, instance.SignalDeadline)
+			result++
+		}
+	}
+
+	if result > 0 {
+		logs.Logf(packageName, MSG_TIMERS_RESTORED, result)
+	}
+}
@@ -0,0 +1,179 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/et/utility"
+)
+
+/**
+* newStepSignal
+* @param name, description, signal string, timeout time.Duration, timeoutGoTo string
+* @return *Step
+**/
+func newStepSignal(name, description, signal string, timeout time.Duration, timeoutGoTo string) (*Step, error) {
+	result := &Step{
+		Name:            name,
+		Description:     description,
+		Type:            TpSignal,
+		Signal:          signal,
+		SignalTimeout:   timeout,
+		TimeoutGoTo:     -1,
+		TimeoutGoToStep: timeoutGoTo,
+	}
+	result.fn = result.runSignal
+
+	return result, nil
+}
+
+/**
+* runSignal
+* Si la señal ya llego su payload pasa al ctx, si no la instancia queda en espera de la señal
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runSignal(flow *Instance, ctx et.Json) (et.Json, error) {
+	if flow.Signals == nil {
+		flow.Signals = make(map[string]et.Json)
+	}
+
+	payload, ok := flow.Signals[s.Signal]
+	if ok {
+		delete(flow.Signals, s.Signal)
+		flow.clearSignal()
+		return payload, nil
+	}
+
+	now := utility.NowTime()
+	if flow.WaitingSignal == s.Signal && !flow.SignalDeadline.IsZero() && !now.Before(flow.SignalDeadline) {
+		flow.clearSignal()
+		if s.TimeoutGoTo == -1 {
+			return et.Json{}, fmt.Errorf(MSG_INSTANCE_SIGNAL_TIMEOUT, s.Signal, s.Name)
+		}
+
+		flow.goTo = s.TimeoutGoTo
+		return ctx, nil
+	}
+
+	if flow.WaitingSignal != s.Signal {
+		flow.WaitingSignal = s.Signal
+		if s.SignalTimeout > 0 {
+			flow.SignalDeadline = now.Add(s.SignalTimeout)
+			if flow.workFlows != nil {
+				flow.workFlows.timers.schedule(flow.Id, flow.SignalDeadline)
+			}
+		}
+	}
+
+	flow.suspend()
+	return ctx, nil
+}
+
+/**
+* clearSignal
+**/
+func (s *Instance) clearSignal() {
+	if !s.SignalDeadline.IsZero() && s.workFlows != nil {
+		s.workFlows.timers.cancel(s.Id)
+	}
+
+	s.WaitingSignal = ""
+	s.SignalDeadline = time.Time{}
+}
+
+/**
+* queueSignal
+* Guarda la señal que llega mientras la instancia esta en ejecucion, se entrega al terminar
+* @param name string, payload et.Json
+**/
+func (s *Instance) queueSignal(name string, payload et.Json) {
+	s.inboxMu.Lock()
+	defer s.inboxMu.Unlock()
+
+	if s.inbox == nil {
+		s.inbox = make(map[string]et.Json)
+	}
+	s.inbox[name] = payload
+}
+
+/**
+* takeSignals
+* @return map[string]et.Json
+**/
+func (s *Instance) takeSignals() map[string]et.Json {
+	s.inboxMu.Lock()
+	defer s.inboxMu.Unlock()
+
+	result := s.inbox
+	s.inbox = nil
+
+	return result
+}
+
+/**
+* deliver
+* Entrega las señales que llegaron durante la ejecucion
+* @param instance *Instance
+**/
+func (s *WorkFlows) deliver(instance *Instance) {
+	for name, payload := range instance.takeSignals() {
+		_, err := s.signal(instance.Id, name, payload)
+		if err != nil {
+			logs.Error(err)
+		}
+	}
+}
+
+/**
+* signal
+* Guarda el payload de la señal y reanuda la instancia si esta esperando esa señal,
+* si la señal llega antes queda guardada hasta que el step la consuma. Con la instancia en
+* ejecucion la señal se encola y se entrega al terminar, los mapas solo se tocan con el bloqueo
+* @param instanceId, name string, payload et.Json
+* @return et.Json, error
+**/
+func (s *WorkFlows) signal(instanceId, name string, payload et.Json) (et.Json, error) {
+	if payload == nil {
+		payload = et.Json{}
+	}
+
+	var instance *Instance
+	err := s.transition(instanceId, func(current *Instance) error {
+		instance = current
+		if instance.Signals == nil {
+			instance.Signals = make(map[string]et.Json)
+		}
+
+		instance.Signals[name] = payload
+		return instance.Save()
+	})
+	if errors.Is(err, errorInstanceRunning) {
+		running, exists := s.loadInstance(instanceId)
+		if !exists {
+			return et.Json{}, errorInstanceNotFound
+		}
+
+		running.queueSignal(name, payload)
+		logs.Logf(packageName, MSG_INSTANCE_SIGNAL_QUEUED, instanceId, name)
+		if running.mu.TryLock() {
+			running.mu.Unlock()
+			s.deliver(running)
+		}
+
+		return et.Json{"instance_id": instanceId, "signal": name}, nil
+	}
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	logs.Logf(packageName, MSG_INSTANCE_SIGNAL, instance.Id, instance.Tag, name)
+	if instance.Status != FlowStatusWaiting || instance.WaitingSignal != name {
+		return instance.ToJson(), nil
+	}
+
+	return s.run(instance.Id, instance.Tag, instance.Current, et.Json{}, et.Json{}, instance.UpdatedBy)
+}
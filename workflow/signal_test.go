@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testSignalFlow
+* @param wf *WorkFlows, tag string, first FnContext, stop bool, timeout time.Duration, timeoutGoTo string
+* @return *Flow
+**/
+func testSignalFlow(wf *WorkFlows, tag string, first FnContext, stop bool, timeout time.Duration, timeoutGoTo string) *Flow {
+	return wf.newFlowFn(tag, "v1", "Signal", "", first, stop, "test").
+		WaitSignal("Approve", "", "approve", timeout, timeoutGoTo).
+		StepFn("Approved", "", testStep("approved", true), true).
+		StepFn("Expired", "", testStep("expired", true), false)
+}
+
+func TestSignalAfterWait(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSignalFlow(wf, "signal_after", testPass, false, 0, "")
+
+	_, err := Run("signal-1", "signal_after", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waiting := waitStatus(t, "signal-1", FlowStatusWaiting)
+	if waiting.WaitingSignal != "approve" {
+		t.Fatalf("expected waiting approve, got %q", waiting.WaitingSignal)
+	}
+
+	_, err = Signal("signal-1", "approve", et.Json{"by": "ana"})
+	if err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	result := waitStatus(t, "signal-1", FlowStatusPending)
+	if result.Ctx.Str("by") != "ana" || !result.Ctx.Bool("approved") || result.WaitingSignal != "" {
+		t.Fatalf("the payload must reach the ctx and the flow continue, ctx %v", result.Ctx)
+	}
+}
+
+func TestSignalBeforeWait(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSignalFlow(wf, "signal_before", testPass, true, 0, "")
+
+	// El primer step tiene stop, la señal llega antes del step que la espera
+	_, err := Run("signal-2", "signal_before", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waitStatus(t, "signal-2", FlowStatusPending)
+
+	_, err = Signal("signal-2", "approve", et.Json{"by": "ana"})
+	if err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+
+	_, err = Continue("signal-2", et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("continue: %v", err)
+	}
+	result := waitStatus(t, "signal-2", FlowStatusPending)
+	if result.Ctx.Str("by") != "ana" || !result.Ctx.Bool("approved") {
+		t.Fatalf("the stored signal must be consumed without waiting, ctx %v", result.Ctx)
+	}
+}
+
+func TestSignalWhileRunning(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	block := func(flow *Instance, ctx et.Json) (et.Json, error) {
+		close(started)
+		<-release
+		return ctx, nil
+	}
+	testSignalFlow(wf, "signal_running", block, false, 0, "")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run("signal-3", "signal_running", 0, et.Json{}, et.Json{}, "test")
+		done <- err
+	}()
+	<-started
+
+	_, err := Signal("signal-3", "approve", et.Json{"by": "ana"})
+	if err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	result := waitStatus(t, "signal-3", FlowStatusPending)
+	if result.Ctx.Str("by") != "ana" || !result.Ctx.Bool("approved") {
+		t.Fatalf("the queued signal must be delivered, ctx %v", result.Ctx)
+	}
+}
+
+func TestSignalTimeoutGoTo(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSignalFlow(wf, "signal_timeout", testPass, false, 50*time.Millisecond, "Expired")
+
+	_, err := Run("signal-4", "signal_timeout", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waiting := waitStatus(t, "signal-4", FlowStatusWaiting)
+	if waiting.SignalDeadline.IsZero() {
+		t.Fatal("a signal with timeout must keep signal_deadline")
+	}
+
+	result := waitStatus(t, "signal-4", FlowStatusDone)
+	if !result.Ctx.Bool("expired") || result.Ctx.Bool("approved") {
+		t.Fatalf("the timeout must go to Expired, ctx %v", result.Ctx)
+	}
+}
+
+func TestSignalTimeoutFails(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSignalFlow(wf, "signal_expired", testPass, false, 50*time.Millisecond, "")
+
+	_, err := Run("signal-5", "signal_expired", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	result := waitStatus(t, "signal-5", FlowStatusFailed)
+	if result.Ctx.Bool("approved") || result.Ctx.Bool("expired") {
+		t.Fatalf("without timeout goto the step must fail, ctx %v", result.Ctx)
+	}
+}
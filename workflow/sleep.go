@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/utility"
+)
+
+/**
+* newStepSleep
+* @param name, description string, duration time.Duration, until string
+* @return *Step
+**/
+func newStepSleep(name, description string, duration time.Duration, until string) (*Step, error) {
+	result := &Step{
+		Name:        name,
+		Description: description,
+		Type:        TpSleep,
+		Duration:    duration,
+		Until:       until,
+	}
+	result.fn = result.runSleep
+
+	return result, nil
+}
+
+/**
+* wakeAt
+* Until se evalua contra el ctx y puede retornar una fecha, un texto RFC3339 o un unix en segundos
+* @param ctx et.Json, now time.Time
+* @return time.Time, error
+**/
+func (s *Step) wakeAt(ctx et.Json, now time.Time) (time.Time, error) {
+	if s.Until == "" {
+		return now.Add(s.Duration), nil
+	}
+
+	result, err := value(s.Until, ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch v := result.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.RFC3339, v)
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	default:
+		return time.Time{}, fmt.Errorf(MSG_INSTANCE_EVALUATE, s.Until, "expression result is not a time")
+	}
+}
+
+/**
+* runSleep
+* La hora de despertar se guarda en la instancia, al reanudar el step termina si ya se cumplio
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runSleep(flow *Instance, ctx et.Json) (et.Json, error) {
+	now := utility.NowTime()
+	if !flow.WakeAt.IsZero() {
+		if now.Before(flow.WakeAt) {
+			flow.suspend()
+			return ctx, nil
+		}
+
+		flow.WakeAt = time.Time{}
+		return ctx, nil
+	}
+
+	wakeAt, err := s.wakeAt(ctx, now)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	if !now.Before(wakeAt) {
+		return ctx, nil
+	}
+
+	if flow.workFlows == nil {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_WORKFLOWS_IS_NIL)
+	}
+
+	flow.WakeAt = wakeAt
+	flow.workFlows.timers.schedule(flow.Id, wakeAt)
+	flow.suspend()
+
+	return ctx, nil
+}
+
+/**
+* wake
+* El status se consulta con el bloqueo tomado, si la instancia aun esta en ejecucion
+* el despertar se reprograma
+* @param instanceId string
+* @return error
+**/
+func (s *WorkFlows) wake(instanceId string) error {
+	instance, exists := s.loadInstance(instanceId)
+	if !exists {
+		return errorInstanceNotFound
+	}
+
+	unlock, err := s.acquire(instance)
+	if errors.Is(err, errorInstanceRunning) {
+		s.timers.schedule(instanceId, utility.NowTime().Add(timersBusyDelay))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if instance.Status != FlowStatusWaiting {
+		unlock()
+		return nil
+	}
+
+	_, err = s.runLocked(context.Background(), instance, -1, et.Json{}, et.Json{}, instance.UpdatedBy)
+	unlock()
+	s.deliver(instance)
+
+	return err
+}
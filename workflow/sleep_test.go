@@ -0,0 +1,84 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testSleepFlow
+* @param wf *WorkFlows, duration time.Duration
+* @return *Flow
+**/
+func testSleepFlow(wf *WorkFlows, duration time.Duration) *Flow {
+	return wf.newFlowFn("sleep", "v1", "Sleep", "", testStep("started", true), false, "test").
+		Sleep("Nap", "", duration).
+		StepFn("End", "", testStep("ended", true), false)
+}
+
+func TestSleepWakes(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	testSleepFlow(wf, 50*time.Millisecond)
+
+	_, err := Run("sleep-1", "sleep", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	waiting := waitStatus(t, "sleep-1", FlowStatusWaiting)
+	if waiting.WakeAt.IsZero() {
+		t.Fatal("a sleeping instance must keep wake_at")
+	}
+
+	done := waitStatus(t, "sleep-1", FlowStatusDone)
+	if !done.Ctx.Bool("ended") {
+		t.Fatalf("the step after the sleep must run, ctx %v", done.Ctx)
+	}
+}
+
+/**
+* testSleepRestart
+* La instancia queda dormida, el proceso se detiene y otro motor la despierta con Restore
+* @param t *testing.T
+**/
+func testSleepRestart(t *testing.T) {
+	t.Helper()
+
+	first := workFlows
+	testSleepFlow(first, 100*time.Millisecond)
+	_, err := Run("sleep-2", "sleep", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	waitStatus(t, "sleep-2", FlowStatusWaiting)
+	first.timers.cancel("sleep-2")
+
+	second := newWorkFlows()
+	workFlows = second
+	testSleepFlow(second, 100*time.Millisecond)
+	n, err := Restore()
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 restored instance, got %d", n)
+	}
+
+	done := waitStatus(t, "sleep-2", FlowStatusDone)
+	if !done.Ctx.Bool("ended") {
+		t.Fatalf("the step after the sleep must run, ctx %v", done.Ctx)
+	}
+}
+
+func TestSleepRestore(t *testing.T) {
+	testWorkFlows(t, NewMemoryStore())
+	testSleepRestart(t)
+}
+
+func TestSleepRestoreWithHooks(t *testing.T) {
+	testWorkFlows(t, nil)
+	testHooks(t)
+	testSleepRestart(t)
+}
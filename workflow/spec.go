@@ -0,0 +1,216 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/logs"
+	"github.com/cgalvisleon/jdb/jdb"
+	"gopkg.in/yaml.v3"
+)
+
+const SpecVersion = "workflow/v1"
+
+type TpFormat string
+
+const (
+	FormatJson TpFormat = "json"
+	FormatYaml TpFormat = "yaml"
+)
+
+var (
+	functions   = make(map[string]FnContext)
+	functionsMu sync.RWMutex
+)
+
+/**
+* RegisterFn
+* Registra una funcion Go por nombre para que los flujos deserializados la puedan usar
+* @param name string, fn FnContext
+* @return void
+**/
+func RegisterFn(name string, fn FnContext) {
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+
+	functions[name] = fn
+}
+
+/**
+* getFunction
+* @param name string
+* @return FnContext
+**/
+func getFunction(name string) FnContext {
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+
+	return functions[name]
+}
+
+/**
+* spec
+* El campo spec del exterior reemplaza al del flujo al serializar, Export no modifica el flujo
+**/
+type spec struct {
+	Spec string `json:"spec"`
+	*Flow
+}
+
+/**
+* Export
+* Genera la especificacion del flujo, es exactamente lo que consumen FlowByDefinition y FlowByParams
+* @param format TpFormat
+* @return ([]byte, error)
+**/
+func (s *Flow) Export(format TpFormat) ([]byte, error) {
+	for _, step := range s.Steps {
+		if err := step.exportable(); err != nil {
+			return nil, err
+		}
+	}
+
+	bt, err := json.Marshal(&spec{Spec: SpecVersion, Flow: s})
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatYaml:
+		var data map[string]interface{}
+		err = json.Unmarshal(bt, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		return yaml.Marshal(data)
+	case FormatJson, "":
+		return bt, nil
+	default:
+		return nil, fmt.Errorf(MSG_SPEC_FORMAT_UNSUPPORTED, format)
+	}
+}
+
+/**
+* parseSpec
+* Acepta la especificacion en JSON o YAML
+* @param bt []byte
+* @return (*Flow, error)
+**/
+func parseSpec(bt []byte) (*Flow, error) {
+	trimmed := bytes.TrimSpace(bt)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		var data map[string]interface{}
+		err := yaml.Unmarshal(bt, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		bt, err = json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result *Flow
+	err := json.Unmarshal(bt, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+
+	if result.Spec != "" && result.Spec != SpecVersion {
+		return nil, fmt.Errorf(MSG_SPEC_VERSION_UNSUPPORTED, result.Spec, SpecVersion)
+	}
+
+	result.bind()
+	return result, nil
+}
+
+/**
+* bind
+* Completa un flujo deserializado: valores por defecto, funciones y modelos
+* @return void
+**/
+func (s *Flow) bind() {
+	if s.TpConsistency == "" {
+		s.TpConsistency = TpConsistencyEventual
+	}
+
+	if s.RetentionTime == 0 {
+		s.RetentionTime = 15 * time.Minute
+	}
+
+	if s.Steps == nil {
+		s.Steps = make([]*Step, 0)
+	}
+
+	for _, step := range s.Steps {
+		if step != nil {
+			step.bind()
+		}
+	}
+
+	s.models = make(map[string]*jdb.Model)
+	for _, model := range s.Models {
+		result, err := getModel(model.Database, model.Name)
+		if err != nil {
+			logs.Error(err)
+			continue
+		}
+
+		s.models[model.Name] = result
+	}
+}
+
+/**
+* legacyParams
+* Convierte los parametros anteriores (definition y stop del step inicial mas steps) a la especificacion
+* @param params et.Json
+* @return (et.Json, error)
+**/
+func legacyParams(params et.Json) (et.Json, error) {
+	steps := params.ArrayJson("steps")
+	if len(steps) == 0 {
+		steps = params.ArrayJson("eteps")
+	}
+
+	result := params.Clone()
+	delete(result, "eteps")
+	delete(result, "definition")
+	delete(result, "stop")
+	delete(result, "createdBy")
+	if createdBy := params.Str("createdBy"); createdBy != "" {
+		result["created_by"] = createdBy
+	}
+	specSteps := []et.Json{{
+		"name":        "Start",
+		"description": MSG_START_WORKFLOW,
+		"type":        TpDefinition,
+		"definition":  params.Str("definition"),
+		"stop":        params.Bool("stop"),
+	}}
+	for i, step := range steps {
+		if step.Str("name") == "" {
+			return nil, fmt.Errorf(MSG_ATTRIBUTE_REQUIRED_STEP, "name", i)
+		}
+		if step.Str("description") == "" {
+			return nil, fmt.Errorf(MSG_ATTRIBUTE_REQUIRED_STEP, "description", i)
+		}
+		if step.Str("type") == "" {
+			step["type"] = TpDefinition
+		}
+
+		specSteps = append(specSteps, step)
+	}
+	result["steps"] = specSteps
+
+	return result, nil
+}
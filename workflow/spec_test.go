@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testSpecFlow
+* @return *Flow
+**/
+func testSpecFlow() *Flow {
+	RegisterFn("spec_test.charge", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		return ctx, nil
+	})
+
+	return newFlow("spec_test", "1.0.0", "Spec", "Round trip", "test").
+		Step("Start", "Inicio", "ctx", false).
+		StepFunction("Charge", "Cobro", "spec_test.charge", false).
+		Timeout(5*time.Second).
+		Retry(3, time.Second, 2, 10*time.Second, 0.1, "unavailable").
+		OnError("declined", "", "Notify", true).
+		WaitSignal("Approve", "Aprobacion", "approved", time.Minute, "").
+		Step("Notify", "Notificar", "ctx", false).
+		Sleep("Pause", "Pausa", time.Second)
+}
+
+func TestSpecRoundTrip(t *testing.T) {
+	for _, format := range []TpFormat{FormatJson, FormatYaml} {
+		flow := testSpecFlow()
+		bt, err := flow.Export(format)
+		if err != nil {
+			t.Fatalf("%s export: %v", format, err)
+		}
+
+		result, err := parseSpec(bt)
+		if err != nil {
+			t.Fatalf("%s parse: %v", format, err)
+		}
+
+		if result.Tag != flow.Tag || result.Spec != SpecVersion || len(result.Steps) != len(flow.Steps) {
+			t.Fatalf("%s: flow header or steps lost", format)
+		}
+		if flow.Spec != "" {
+			t.Fatalf("%s: export must not modify the flow", format)
+		}
+
+		charge := result.Steps[1]
+		if charge.Function != "spec_test.charge" || charge.fn == nil {
+			t.Fatalf("%s: registered function must be bound", format)
+		}
+		if charge.Timeout != 5*time.Second {
+			t.Fatalf("%s: expected timeout 5s, got %s", format, charge.Timeout)
+		}
+		if charge.Retry == nil || charge.Retry.MaxAttempts != 3 || charge.Retry.Codes[0] != "unavailable" {
+			t.Fatalf("%s: retry lost", format)
+		}
+		if len(charge.OnError) != 1 || charge.OnError[0].GoToStep != "Notify" || !charge.OnError[0].Expose {
+			t.Fatalf("%s: on_error lost", format)
+		}
+
+		approve := result.Steps[2]
+		if approve.Signal != "approved" || approve.TimeoutGoTo != flow.Steps[2].TimeoutGoTo {
+			t.Fatalf("%s: signal lost, timeout_go_to:%d", format, approve.TimeoutGoTo)
+		}
+
+		if problems := result.Validate().Errors(); len(problems) > 0 {
+			t.Fatalf("%s: parsed flow must validate: %s", format, problems)
+		}
+	}
+}
+
+func TestSpecDefaults(t *testing.T) {
+	spec := []byte(`
+tag: defaults
+steps:
+  - name: Route
+    description: Ruta
+    type: definition
+    definition: ctx
+    switch: kind
+    cases:
+      a: 1
+  - name: Wait
+    description: Espera
+    type: signal
+    signal: approved
+    on_error:
+      - code: x
+        go_to_step: Route
+`)
+
+	result, err := parseSpec(spec)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if result.Steps[0].DefaultGoTo != -1 {
+		t.Fatalf("missing default_go_to must continue to the next step, got %d", result.Steps[0].DefaultGoTo)
+	}
+	if result.Steps[1].TimeoutGoTo != -1 {
+		t.Fatalf("missing timeout_go_to must not go to step 0, got %d", result.Steps[1].TimeoutGoTo)
+	}
+	if result.Steps[1].OnError[0].GoTo != -1 {
+		t.Fatalf("missing go_to must be -1 before resolve, got %d", result.Steps[1].OnError[0].GoTo)
+	}
+	if result.TpConsistency != TpConsistencyEventual || result.RetentionTime != 15*time.Minute {
+		t.Fatal("flow defaults must be applied")
+	}
+}
+
+func TestSpecVersion(t *testing.T) {
+	if _, err := parseSpec([]byte(`{"spec":"workflow/v0","tag":"old"}`)); err == nil {
+		t.Fatal("unsupported spec version must fail")
+	}
+}
+
+func TestSpecExportFn(t *testing.T) {
+	flow := newFlow("spec_fn", "1.0.0", "Fn", "Fn", "test").
+		StepFn("Start", "Inicio", func(flow *Instance, ctx et.Json) (et.Json, error) {
+			return ctx, nil
+		}, false)
+
+	if _, err := flow.Export(FormatJson); err == nil {
+		t.Fatal("unregistered Go function must not be exported")
+	}
+}
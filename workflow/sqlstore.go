@@ -0,0 +1,467 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/timezone"
+	"github.com/cgalvisleon/jdb/jdb"
+)
+
+const sqlTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+/**
+* sqlTime
+* Las fechas se guardan en UTC con ancho fijo para que ORDER BY las ordene como texto
+* @param t time.Time
+* @return string
+**/
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeLayout)
+}
+
+var sqlDefinition = []string{
+	`CREATE TABLE IF NOT EXISTS workflow_flows (
+		tag TEXT PRIMARY KEY,
+		version TEXT,
+		name TEXT,
+		data TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE TABLE IF NOT EXISTS workflow_instances (
+		id TEXT PRIMARY KEY,
+		tag TEXT,
+		status TEXT,
+		revision INTEGER,
+		current INTEGER,
+		parent_id TEXT,
+		data TEXT,
+		created_at TEXT,
+		updated_at TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS workflow_instances_tag_status_idx ON workflow_instances(tag, status)`,
+	`CREATE TABLE IF NOT EXISTS workflow_results (
+		instance_id TEXT,
+		step INTEGER,
+		attempt INTEGER,
+		error TEXT,
+		data TEXT,
+		created_at TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS workflow_results_instance_idx ON workflow_results(instance_id)`,
+	`CREATE TABLE IF NOT EXISTS workflow_rollbacks (
+		instance_id TEXT,
+		step INTEGER,
+		error TEXT,
+		data TEXT,
+		PRIMARY KEY (instance_id, step)
+	)`,
+	`CREATE TABLE IF NOT EXISTS workflow_history (
+		instance_id TEXT,
+		type TEXT,
+		step INTEGER,
+		status TEXT,
+		actor TEXT,
+		message TEXT,
+		created_at TEXT
+	)`,
+	`CREATE INDEX IF NOT EXISTS workflow_history_instance_idx ON workflow_history(instance_id)`,
+}
+
+/**
+* SqlStore
+* Store sobre una base de datos de jdb (postgres o sqlite), los datos se guardan como json
+* y las columnas de busqueda aparte
+**/
+type SqlStore struct {
+	db *jdb.DB
+}
+
+/**
+* NewSqlStore
+* Crea las tablas si no existen
+* @param db *jdb.DB
+* @return *SqlStore, error
+**/
+func NewSqlStore(db *jdb.DB) (*SqlStore, error) {
+	result := &SqlStore{
+		db: db,
+	}
+
+	for _, sql := range sqlDefinition {
+		_, err := jdb.Query(db, sql)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* tx
+* Ejecuta las sentencias en una transaccion
+* @param fn func(tx *jdb.Tx) error
+* @return error
+**/
+func (s *SqlStore) tx(fn func(tx *jdb.Tx) error) error {
+	tx := jdb.NewTx()
+	err := fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+/**
+* first
+* @param items et.Items
+* @return et.Json, bool
+**/
+func first(items et.Items) (et.Json, bool) {
+	if len(items.Result) == 0 {
+		return nil, false
+	}
+
+	return items.Result[0], true
+}
+
+/**
+* GetFlow
+* @param tag string
+* @return *Flow, error
+**/
+func (s *SqlStore) GetFlow(tag string) (*Flow, error) {
+	items, err := jdb.Query(s.db, `SELECT data FROM workflow_flows WHERE tag = $1`, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	item, ok := first(items)
+	if !ok {
+		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+
+	return parseSpec([]byte(item.Str("data")))
+}
+
+/**
+* SetFlow
+* @param flow *Flow
+* @return error
+**/
+func (s *SqlStore) SetFlow(flow *Flow) error {
+	bt, err := flow.Serialize()
+	if err != nil {
+		return err
+	}
+
+	_, err = jdb.Query(s.db, `INSERT INTO workflow_flows (tag, version, name, data, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tag) DO UPDATE SET version = EXCLUDED.version, name = EXCLUDED.name, data = EXCLUDED.data, updated_at = EXCLUDED.updated_at`,
+		flow.Tag, flow.Version, flow.Name, string(bt), sqlTime(timezone.NowTime()))
+	return err
+}
+
+/**
+* DeleteFlow
+* @param tag string
+* @return error
+**/
+func (s *SqlStore) DeleteFlow(tag string) error {
+	_, err := jdb.Query(s.db, `DELETE FROM workflow_flows WHERE tag = $1`, tag)
+	return err
+}
+
+/**
+* ListFlows
+* @return []*Flow, error
+**/
+func (s *SqlStore) ListFlows() ([]*Flow, error) {
+	items, err := jdb.Query(s.db, `SELECT data FROM workflow_flows ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Flow, 0, len(items.Result))
+	for _, item := range items.Result {
+		flow, err := parseSpec([]byte(item.Str("data")))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, flow)
+	}
+
+	return result, nil
+}
+
+/**
+* instance
+* @param item et.Json
+* @return *Instance, error
+**/
+func (s *SqlStore) instance(item et.Json) (*Instance, error) {
+	var result *Instance
+	err := json.Unmarshal([]byte(item.Str("data")), &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/**
+* GetInstance
+* @param id string
+* @return *Instance, error
+**/
+func (s *SqlStore) GetInstance(id string) (*Instance, error) {
+	items, err := jdb.Query(s.db, `SELECT data FROM workflow_instances WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	item, ok := first(items)
+	if !ok {
+		return nil, errorInstanceNotFound
+	}
+
+	return s.instance(item)
+}
+
+/**
+* SetInstance
+* La instancia, sus compensaciones, resultados e historial se guardan en la misma transaccion.
+* Con revision 0 solo se inserta si no existe y con otra revision solo se actualiza si la
+* guardada es la esperada, igual que MemoryStore y FileStore
+* @param instance *Instance, revision int64, changes *Changes
+* @return error
+**/
+func (s *SqlStore) SetInstance(instance *Instance, revision int64, changes *Changes) error {
+	bt, err := instance.Serialize()
+	if err != nil {
+		return err
+	}
+
+	return s.tx(func(tx *jdb.Tx) error {
+		var items et.Items
+		if revision == 0 {
+			items, err = jdb.QueryTx(s.db, tx, `INSERT INTO workflow_instances (id, tag, status, revision, current, parent_id, data, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+				ON CONFLICT (id) DO NOTHING
+				RETURNING revision`,
+				instance.Id, instance.Tag, instance.Status, instance.Revision, instance.Current, instance.ParentId, string(bt),
+				sqlTime(instance.CreatedAt), sqlTime(instance.UpdatedAt))
+		} else {
+			items, err = jdb.QueryTx(s.db, tx, `UPDATE workflow_instances SET status = $1, revision = $2, current = $3, data = $4, updated_at = $5
+				WHERE id = $6 AND revision = $7
+				RETURNING revision`,
+				instance.Status, instance.Revision, instance.Current, string(bt), sqlTime(instance.UpdatedAt), instance.Id, revision)
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, ok := first(items); !ok {
+			current := int64(0)
+			items, err = jdb.QueryTx(s.db, tx, `SELECT revision FROM workflow_instances WHERE id = $1`, instance.Id)
+			if item, ok := first(items); ok && err == nil {
+				current = int64(item.Int("revision"))
+			}
+
+			return &ConflictError{InstanceId: instance.Id, Expected: revision, Current: current}
+		}
+
+		_, err = jdb.QueryTx(s.db, tx, `DELETE FROM workflow_rollbacks WHERE instance_id = $1`, instance.Id)
+		if err != nil {
+			return err
+		}
+
+		for step, rollback := range instance.Rollbacks {
+			data, err := rollback.Serialize()
+			if err != nil {
+				return err
+			}
+
+			_, err = jdb.QueryTx(s.db, tx, `INSERT INTO workflow_rollbacks (instance_id, step, error, data) VALUES ($1, $2, $3, $4)`,
+				instance.Id, step, rollback.Error, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, result := range changes.Results {
+			err = s.setResult(tx, instance.Id, result)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range changes.History {
+			err = s.appendHistory(tx, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+/**
+* DeleteInstance
+* @param id string
+* @return error
+**/
+func (s *SqlStore) DeleteInstance(id string) error {
+	return s.tx(func(tx *jdb.Tx) error {
+		for _, table := range []string{"workflow_results", "workflow_rollbacks", "workflow_history"} {
+			_, err := jdb.QueryTx(s.db, tx, fmt.Sprintf(`DELETE FROM %s WHERE instance_id = $1`, table), id)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := jdb.QueryTx(s.db, tx, `DELETE FROM workflow_instances WHERE id = $1`, id)
+		return err
+	})
+}
+
+/**
+* ListInstances
+* @param query Query
+* @return []*Instance, error
+**/
+func (s *SqlStore) ListInstances(query Query) ([]*Instance, error) {
+	where := make([]string, 0)
+	args := make([]interface{}, 0)
+	if query.Tag != "" {
+		args = append(args, query.Tag)
+		where = append(where, fmt.Sprintf("tag = $%d", len(args)))
+	}
+
+	if len(query.Status) > 0 {
+		in := make([]string, 0, len(query.Status))
+		for _, status := range query.Status {
+			args = append(args, status)
+			in = append(in, fmt.Sprintf("$%d", len(args)))
+		}
+		where = append(where, fmt.Sprintf("status IN (%s)", strings.Join(in, ", ")))
+	}
+
+	sql := `SELECT data FROM workflow_instances`
+	if len(where) > 0 {
+		sql += " WHERE " + strings.Join(where, " AND ")
+	}
+	sql += " ORDER BY created_at"
+	if query.Limit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", query.Limit)
+	}
+
+	items, err := jdb.Query(s.db, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Instance, 0, len(items.Result))
+	for _, item := range items.Result {
+		instance, err := s.instance(item)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, instance)
+	}
+
+	return result, nil
+}
+
+/**
+* setResult
+* @param tx *jdb.Tx, instanceId string, result *Result
+* @return error
+**/
+func (s *SqlStore) setResult(tx *jdb.Tx, instanceId string, result *Result) error {
+	data, err := result.Serialize()
+	if err != nil {
+		return err
+	}
+
+	_, err = jdb.QueryTx(s.db, tx, `INSERT INTO workflow_results (instance_id, step, attempt, error, data, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		instanceId, result.Step, result.Attempt, result.Error, data, sqlTime(timezone.NowTime()))
+	return err
+}
+
+/**
+* GetResults
+* @param instanceId string
+* @return []*Result, error
+**/
+func (s *SqlStore) GetResults(instanceId string) ([]*Result, error) {
+	items, err := jdb.Query(s.db, `SELECT data FROM workflow_results WHERE instance_id = $1 ORDER BY created_at`, instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Result, 0, len(items.Result))
+	for _, item := range items.Result {
+		var res *Result
+		err := json.Unmarshal([]byte(item.Str("data")), &res)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, res)
+	}
+
+	return result, nil
+}
+
+/**
+* appendHistory
+* @param tx *jdb.Tx, entry *HistoryEntry
+* @return error
+**/
+func (s *SqlStore) appendHistory(tx *jdb.Tx, entry *HistoryEntry) error {
+	_, err := jdb.QueryTx(s.db, tx, `INSERT INTO workflow_history (instance_id, type, step, status, actor, message, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.InstanceId, entry.Type, entry.Step, entry.Status, entry.Actor, entry.Message, sqlTime(entry.CreatedAt))
+	return err
+}
+
+/**
+* GetHistory
+* @param instanceId string
+* @return []*HistoryEntry, error
+**/
+func (s *SqlStore) GetHistory(instanceId string) ([]*HistoryEntry, error) {
+	items, err := jdb.Query(s.db, `SELECT type, step, status, actor, message, created_at FROM workflow_history WHERE instance_id = $1 ORDER BY created_at`, instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*HistoryEntry, 0, len(items.Result))
+	for _, item := range items.Result {
+		createdAt, err := time.Parse(sqlTimeLayout, item.Str("created_at"))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &HistoryEntry{
+			InstanceId: instanceId,
+			Type:       TpHistory(item.Str("type")),
+			Step:       item.Int("step"),
+			Status:     FlowStatus(item.Str("status")),
+			Actor:      item.Str("actor"),
+			Message:    item.Str("message"),
+			CreatedAt:  createdAt,
+		})
+	}
+
+	return result, nil
+}
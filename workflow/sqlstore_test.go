@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/jdb/drivers/sqlite"
+	"github.com/cgalvisleon/jdb/jdb"
+)
+
+/**
+* testSqlStore
+* SqlStore sobre un sqlite temporal, se omite si el driver no esta disponible
+* @param t *testing.T
+* @return *SqlStore
+**/
+func testSqlStore(t *testing.T) *SqlStore {
+	t.Helper()
+
+	db, err := jdb.ConnectTo(jdb.ConnectParams{
+		Id:     "workflow_test",
+		Driver: jdb.SqliteDriver,
+		Name:   "workflow_test",
+		Params: &sqlite.Connection{
+			Database: filepath.Join(t.TempDir(), "workflow.db"),
+		},
+	})
+	if err != nil {
+		t.Skipf("sqlite: %v", err)
+	}
+
+	result, err := NewSqlStore(db)
+	if err != nil {
+		t.Fatalf("new sql store: %v", err)
+	}
+
+	return result
+}
+
+func TestSqlStoreRevision(t *testing.T) {
+	testStoreRevision(t, testSqlStore(t))
+}
+
+func TestSqlStoreList(t *testing.T) {
+	testStoreList(t, testSqlStore(t))
+}
+
+func TestSqlStoreHistory(t *testing.T) {
+	testStoreHistory(t, testSqlStore(t))
+}
+
+func TestSqlStoreOrderUTC(t *testing.T) {
+	store := testSqlStore(t)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bogota := time.FixedZone("bogota", -5*60*60)
+	tokyo := time.FixedZone("tokyo", 9*60*60)
+	// Como texto en su zona "second" es menor que "first"
+	first := testInstance("first", "orders", FlowStatusPending)
+	first.CreatedAt = base.In(tokyo)
+	second := testInstance("second", "orders", FlowStatusPending)
+	second.CreatedAt = base.Add(time.Hour).In(bogota)
+	third := testInstance("third", "orders", FlowStatusPending)
+	third.CreatedAt = base.Add(2 * time.Hour).Add(time.Nanosecond)
+
+	for _, instance := range []*Instance{third, second, first} {
+		if err := save(store, instance); err != nil {
+			t.Fatalf("save %s: %v", instance.Id, err)
+		}
+	}
+
+	result, err := store.ListInstances(Query{Tag: "orders"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	expected := []string{"first", "second", "third"}
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d instances, got %d", len(expected), len(result))
+	}
+	for i, instance := range result {
+		if instance.Id != expected[i] {
+			t.Fatalf("position %d: expected %s, got %s", i, expected[i], instance.Id)
+		}
+	}
+}
@@ -1,13 +1,15 @@
 package workflow
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Knetic/govaluate"
 	"github.com/cgalvisleon/et/et"
-	"github.com/cgalvisleon/et/logs"
-	"github.com/dop251/goja"
+	"github.com/cgalvisleon/workflow/vm"
 )
 
 type TpStep string
@@ -15,21 +17,56 @@ type TpStep string
 const (
 	TpFn         TpStep = "function"
 	TpDefinition TpStep = "definition"
+	TpParallel   TpStep = "parallel"
+	TpForEach    TpStep = "foreach"
+	TpSubFlow    TpStep = "subflow"
+	TpSignal     TpStep = "signal"
+	TpSleep      TpStep = "sleep"
 )
 
 type FnContext func(flow *Instance, ctx et.Json) (et.Json, error)
 
 type Step struct {
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Type        TpStep    `json:"type"`
-	Stop        bool      `json:"stop"`
-	Expression  string    `json:"expression"`
-	YesGoTo     int       `json:"yes_go_to"`
-	NoGoTo      int       `json:"no_go_to"`
-	Definition  string    `json:"definition"`
-	fn          FnContext `json:"-"`
-	rollbacks   FnContext `json:"-"`
+	Name               string            `json:"name"`
+	Description        string            `json:"description"`
+	Type               TpStep            `json:"type"`
+	Stop               bool              `json:"stop"`
+	Timeout            time.Duration     `json:"timeout"`
+	Retry              *Retry            `json:"retry"`
+	Input              map[string]string `json:"input"`
+	Output             map[string]string `json:"output"`
+	OnError            []*OnError        `json:"on_error"`
+	Expression         string            `json:"expression"`
+	YesGoTo            int               `json:"yes_go_to"`
+	NoGoTo             int               `json:"no_go_to"`
+	YesGoToStep        string            `json:"yes_go_to_step"`
+	NoGoToStep         string            `json:"no_go_to_step"`
+	Switch             string            `json:"switch"`
+	Cases              map[string]int    `json:"cases"`
+	CasesStep          map[string]string `json:"cases_step"`
+	DefaultGoTo        int               `json:"default_go_to"`
+	DefaultGoToStep    string            `json:"default_go_to_step"`
+	Definition         string            `json:"definition"`
+	Function           string            `json:"function"`
+	RollbackFunction   string            `json:"rollback_function"`
+	RollbackDefinition string            `json:"rollback_definition"`
+	Branches           []*Step           `json:"branches"`
+	Wait               int               `json:"wait"`
+	Merge              TpMerge           `json:"merge"`
+	Path               string            `json:"path"`
+	Concurrency        int               `json:"concurrency"`
+	StopOnError        bool              `json:"stop_on_error"`
+	Body               []*Step           `json:"body"`
+	FlowTag            string            `json:"flow_tag"`
+	Mapping            map[string]string `json:"mapping"`
+	Signal             string            `json:"signal"`
+	SignalTimeout      time.Duration     `json:"signal_timeout"`
+	TimeoutGoTo        int               `json:"timeout_go_to"`
+	TimeoutGoToStep    string            `json:"timeout_go_to_step"`
+	Duration           time.Duration     `json:"duration"`
+	Until              string            `json:"until"`
+	fn                 FnContext         `json:"-"`
+	rollbacks          FnContext         `json:"-"`
 }
 
 /**
@@ -62,54 +99,110 @@ func newStepDefinition(name, description string, definition string, stop bool) (
 		Stop:        stop,
 		Definition:  definition,
 	}
-	result.fn = func(flow *Instance, ctx et.Json) (et.Json, error) {
-		flow.vm.Set("instance", flow)
-		flow.vm.Set("ctx", flow.Ctx)
-		flow.vm.Set("ctxs", flow.Ctxs)
-		flow.vm.Set("pinnedData", flow.PinnedData)
-		for k, v := range flow.models {
-			flow.vm.Set(k, v)
-		}
+	result.fn = result.runScript
 
-		if definition == "" {
-			return et.Json{}, fmt.Errorf(MSG_INSTANCE_DEFINITION_EMPTY)
-		}
+	return result, nil
+}
 
-		value, err := flow.vm.Run(definition)
-		if err != nil {
-			return et.Json{}, err
-		}
+/**
+* runScript
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runScript(flow *Instance, ctx et.Json) (et.Json, error) {
+	return flow.runDefinition(flow.vm, s.Definition, ctx)
+}
 
-		if flow.isDebug {
-			logs.Debugf("stepDefinition:%s", value.String())
-		}
+/**
+* runRollbackScript
+* Se ejecuta en la vm de la instancia con el snapshot del ctx del step, igual que los rollbacks Go
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runRollbackScript(flow *Instance, ctx et.Json) (et.Json, error) {
+	return flow.runDefinition(flow.vm, s.RollbackDefinition, ctx)
+}
 
-		result := flow.vm.Get("result")
-		if result == goja.Undefined() {
-			return flow.Ctx, nil
-		}
+/**
+* UnmarshalJSON
+* Sin default_go_to el switch continua al siguiente step y sin timeout_go_to la señal
+* continua al siguiente step en lugar de volver al step 0
+* @param data []byte
+* @return error
+**/
+func (s *Step) UnmarshalJSON(data []byte) error {
+	type step Step
+	s.DefaultGoTo = -1
+	s.TimeoutGoTo = -1
 
-		if result == goja.Null() {
-			return flow.Ctx, nil
+	return json.Unmarshal(data, (*step)(s))
+}
+
+/**
+* bind
+* Enlaza las funciones de un step deserializado, las funciones Go se buscan por nombre en el registro
+* @return void
+**/
+func (s *Step) bind() {
+	switch s.Type {
+	case TpFn:
+		if s.Function != "" {
+			s.fn = getFunction(s.Function)
+		}
+	case TpDefinition:
+		s.fn = s.runScript
+	case TpParallel:
+		s.fn = s.runParallel
+		for _, branch := range s.Branches {
+			branch.bind()
+		}
+	case TpForEach:
+		s.fn = s.runForEach
+		for _, body := range s.Body {
+			body.bind()
 		}
+	case TpSubFlow:
+		s.fn = s.runSubFlow
+	case TpSignal:
+		s.fn = s.runSignal
+	case TpSleep:
+		s.fn = s.runSleep
+	}
+
+	if s.RollbackFunction != "" {
+		s.rollbacks = getFunction(s.RollbackFunction)
+	} else if s.RollbackDefinition != "" {
+		s.rollbacks = s.runRollbackScript
+	}
+}
 
-		if result == nil {
-			return flow.Ctx, nil
+/**
+* exportable
+* Un step solo se puede exportar si sus funciones Go estan registradas por nombre
+* @return error
+**/
+func (s *Step) exportable() error {
+	if s.Type == TpFn && s.Function == "" {
+		return fmt.Errorf(MSG_SPEC_FUNCTION_NOT_REGISTERED, s.Name)
+	}
+
+	if s.rollbacks != nil && s.RollbackFunction == "" && s.RollbackDefinition == "" {
+		return fmt.Errorf(MSG_SPEC_FUNCTION_NOT_REGISTERED, s.Name)
+	}
+
+	for _, branch := range s.Branches {
+		if err := branch.exportable(); err != nil {
+			return err
 		}
+	}
 
-		switch v := result.Export().(type) {
-		case map[string]interface{}:
-			return et.Json(v), nil
-		case et.Json:
-			return v, nil
-		default:
-			return et.Json{
-				"result": v,
-			}, nil
+	for _, body := range s.Body {
+		if err := body.exportable(); err != nil {
+			return err
 		}
 	}
 
-	return result, nil
+	return nil
 }
 
 /**
@@ -123,14 +216,125 @@ func (s *Step) run(flow *Instance, ctx et.Json) (et.Json, error) {
 	}
 
 	flow.SetStatus(FlowStatusRunning)
-	result, err := s.fn(flow, ctx)
+	if len(s.Input) > 0 {
+		ctx = s.input(ctx)
+	}
+
+	var result et.Json
+	var err error
+	if s.Timeout > 0 {
+		result, err = s.runTimeout(flow, ctx)
+	} else {
+		result, err = s.fn(flow, ctx)
+	}
 	if err != nil {
 		return et.Json{}, err
 	}
 
+	if len(s.Output) > 0 {
+		return s.output(flow, result), nil
+	}
+
 	return result, nil
 }
 
+/**
+* input
+* Lo que ve el step: cada llave toma el valor de la ruta indicada del ctx
+* @param ctx et.Json
+* @return et.Json
+**/
+func (s *Step) input(ctx et.Json) et.Json {
+	result := et.Json{}
+	for key, path := range s.Input {
+		val, ok := getPath(ctx, path)
+		if ok {
+			result[key] = val
+		}
+	}
+
+	return result
+}
+
+/**
+* output
+* Escribe en el ctx de la instancia cada ruta destino con el valor de la ruta del resultado,
+* una ruta vacia toma el resultado completo. Retorna solo las llaves raiz modificadas
+* @param flow *Instance, result et.Json
+* @return et.Json
+**/
+func (s *Step) output(flow *Instance, result et.Json) et.Json {
+	roots := et.Json{}
+	for dest, path := range s.Output {
+		val, ok := getPath(result, path)
+		if !ok {
+			continue
+		}
+
+		root := setPath(flow.Ctx, dest, val)
+		roots[root] = flow.Ctx[root]
+	}
+
+	return roots
+}
+
+/**
+* runTimeout
+* Las funciones Go y las definitions corren en una goroutine sobre una copia de la instancia, al vencer
+* el timeout se interrumpen y se abortan para que no modifiquen la instancia, el step retorna en el
+* plazo aunque la funcion no consulte flow.Context(). Los demas steps reciben el contexto con el timeout
+* @params flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runTimeout(flow *Instance, ctx et.Json) (et.Json, error) {
+	c, cancel := context.WithTimeout(flow.Context(), s.Timeout)
+	defer cancel()
+
+	timeoutErr := NewError(ErrorCodeTimeout, MSG_INSTANCE_STEP_TIMEOUT, s.Name, s.Timeout)
+	if s.Type != TpFn && s.Type != TpDefinition {
+		restore := flow.withContext(c)
+		result, err := s.fn(flow, ctx)
+		restore()
+		if err != nil && errors.Is(c.Err(), context.DeadlineExceeded) {
+			return et.Json{}, timeoutErr
+		}
+
+		return result, err
+	}
+
+	fork := flow.fork(c)
+	done := make(chan *resultFn, 1)
+	go func() {
+		result, err := s.fn(fork, ctx)
+		done <- &resultFn{Result: result, Error: err}
+	}()
+
+	var res *resultFn
+	select {
+	case res = <-done:
+	case <-c.Done():
+		select {
+		case res = <-done:
+		default:
+		}
+	}
+
+	if res != nil {
+		flow.join(fork)
+		return res.Result, res.Error
+	}
+
+	err := c.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = timeoutErr
+	}
+	fork.abort(err)
+	fork.vm.Interrupt(err)
+	flow.vm = vm.New()
+
+	return et.Json{}, err
+}
+
 /**
 * Serialize
 * @return ([]byte, error)
@@ -179,30 +383,121 @@ func (s *Step) ifElse(expression string, yesGoTo int, noGoTo int) *Step {
 }
 
 /**
-* evaluate
-* @param ctx et.Json
-* @return bool, error
+* ifElseByName
+* @param expression string, yesGoTo, noGoTo string
+* @return *Step
 **/
-func (s *Step) evaluate(ctx et.Json, instance *Instance) (bool, error) {
-	resultError := func(err error) (bool, error) {
-		return false, fmt.Errorf(MSG_INSTANCE_EVALUATE, s.Expression, err.Error())
+func (s *Step) ifElseByName(expression string, yesGoTo, noGoTo string) *Step {
+	s.YesGoToStep = yesGoTo
+	s.NoGoToStep = noGoTo
+	if expression != "" {
+		s.Expression = expression
 	}
 
-	instance.SetStatus(FlowStatusRunning)
-	evalueExpression, err := govaluate.NewEvaluableExpression(s.Expression)
+	return s
+}
+
+/**
+* goToByName
+* Con un nombre se ejecuta el step indicado, con un indice (IfElse) se continua en el siguiente al destino
+* @param ok bool
+* @return bool
+**/
+func (s *Step) goToByName(ok bool) bool {
+	if ok {
+		return s.YesGoToStep != ""
+	}
+
+	return s.NoGoToStep != ""
+}
+
+/**
+* switchCases
+* @param expression string, cases map[string]int, defaultGoTo int
+* @return *Step
+**/
+func (s *Step) switchCases(expression string, cases map[string]int, defaultGoTo int) *Step {
+	s.Switch = expression
+	s.Cases = cases
+	s.DefaultGoTo = defaultGoTo
+
+	return s
+}
+
+/**
+* switchCasesByName
+* @param expression string, cases map[string]string, defaultGoTo string
+* @return *Step
+**/
+func (s *Step) switchCasesByName(expression string, cases map[string]string, defaultGoTo string) *Step {
+	s.Switch = expression
+	s.CasesStep = cases
+	s.DefaultGoToStep = defaultGoTo
+	s.DefaultGoTo = -1
+
+	return s
+}
+
+/**
+* value
+* @param expression string, ctx et.Json
+* @return interface{}, error
+**/
+func value(expression string, ctx et.Json) (interface{}, error) {
+	resultError := func(err error) (interface{}, error) {
+		return nil, fmt.Errorf(MSG_INSTANCE_EVALUATE, expression, err.Error())
+	}
+
+	evalueExpression, err := govaluate.NewEvaluableExpression(expression)
 	if err != nil {
 		return resultError(err)
 	}
 
-	ok, err := evalueExpression.Evaluate(ctx)
+	result, err := evalueExpression.Evaluate(ctx)
 	if err != nil {
 		return resultError(err)
 	}
 
+	return result, nil
+}
+
+/**
+* evaluate
+* @param ctx et.Json
+* @return bool, error
+**/
+func (s *Step) evaluate(ctx et.Json, instance *Instance) (bool, error) {
+	instance.SetStatus(FlowStatusRunning)
+	ok, err := value(s.Expression, ctx)
+	if err != nil {
+		return false, err
+	}
+
 	switch v := ok.(type) {
 	case bool:
 		return v, nil
 	default:
-		return resultError(fmt.Errorf("expression result is not a boolean"))
+		return false, fmt.Errorf(MSG_INSTANCE_EVALUATE, s.Expression, "expression result is not a boolean")
 	}
 }
+
+/**
+* evaluateSwitch
+* El resultado de la expresion se compara con los cases como texto, sin case se usa DefaultGoTo
+* @param ctx et.Json, instance *Instance
+* @return int, string, error
+**/
+func (s *Step) evaluateSwitch(ctx et.Json, instance *Instance) (int, string, error) {
+	instance.SetStatus(FlowStatusRunning)
+	result, err := value(s.Switch, ctx)
+	if err != nil {
+		return -1, "", err
+	}
+
+	key := fmt.Sprintf("%v", result)
+	if goTo, ok := s.Cases[key]; ok {
+		return goTo, key, nil
+	}
+
+	return s.DefaultGoTo, key, nil
+}
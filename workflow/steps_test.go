@@ -0,0 +1,83 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestStepTimeoutReturnsAtDeadline(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+		<-finished
+	})
+
+	wf.newFlowFn("timeout", "v1", "Timeout", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		defer close(finished)
+		// Ignora flow.Context() y sigue despues del timeout
+		<-release
+		flow.SetPinnedData("late", true)
+		flow.Save()
+		return et.Json{"late": true}, nil
+	}, false, "test").
+		Timeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := Run("timeout-1", "timeout", 0, et.Json{}, et.Json{}, "test")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("the step must return at the deadline, took %v", elapsed)
+	}
+	if errorCode(err) != ErrorCodeTimeout {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+
+	failed := waitStatus(t, "timeout-1", FlowStatusFailed)
+	revision := failed.Revision
+	release <- struct{}{}
+	<-finished
+
+	result, err := getStore().GetInstance("timeout-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if result.Revision != revision || result.PinnedData["late"] != nil || result.Ctx["late"] != nil {
+		t.Fatal("the abandoned step must not write to the instance")
+	}
+}
+
+func TestStepTimeoutKeepsResult(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("timeout", "v1", "Timeout", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		flow.SetPinnedData("on_time", true)
+		return et.Json{"on_time": true}, nil
+	}, false, "test").
+		Timeout(time.Second)
+
+	_, err := Run("timeout-2", "timeout", 0, et.Json{}, et.Json{}, "test")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	result := waitStatus(t, "timeout-2", FlowStatusDone)
+	if result.PinnedData["on_time"] != true || result.Ctx["on_time"] != true {
+		t.Fatalf("a step that ends in time keeps its changes, ctx %v pinned %v", result.Ctx, result.PinnedData)
+	}
+}
+
+func TestDefinitionReadsCtx(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("definition_ctx", "v1", "Definition", "", testPass, false, "test").
+		Step("Total", "", "result = { total: ctx.order.total * 2, lines: ctx.order.lines.length }", false)
+
+	ctx := testVisited(t, "definition-1", "definition_ctx", et.Json{"order": et.Json{
+		"total": 15,
+		"lines": []et.Json{{"qty": 1}, {"qty": 2}},
+	}})
+	if ctx.Int("total") != 30 || ctx.Int("lines") != 2 {
+		t.Fatalf("the definition must read the ctx keys, ctx %v", ctx)
+	}
+}
@@ -0,0 +1,491 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+	"github.com/cgalvisleon/et/event"
+	"github.com/cgalvisleon/et/logs"
+)
+
+type Query struct {
+	Tag    string       `json:"tag"`
+	Status []FlowStatus `json:"status"`
+	Limit  int          `json:"limit"`
+}
+
+/**
+* match
+* @param instance *Instance
+* @return bool
+**/
+func (s Query) match(instance *Instance) bool {
+	if s.Tag != "" && instance.Tag != s.Tag {
+		return false
+	}
+
+	if len(s.Status) > 0 && !slices.Contains(s.Status, instance.Status) {
+		return false
+	}
+
+	return true
+}
+
+type HistoryEntry struct {
+	InstanceId string     `json:"instance_id"`
+	Type       TpHistory  `json:"type"`
+	Step       int        `json:"step"`
+	Status     FlowStatus `json:"status"`
+	Actor      string     `json:"actor"`
+	Message    string     `json:"message"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+/**
+* Changes
+* Resultados e historial generados desde el ultimo guardado de la instancia
+**/
+type Changes struct {
+	Results []*Result
+	History []*HistoryEntry
+}
+
+/**
+* Store
+* SetInstance es compare-and-set: solo guarda si la revision guardada es revision,
+* si no retorna *ConflictError. instance.Revision ya trae la nueva revision y changes
+* se escribe junto con la instancia, con un conflicto no se escribe nada
+**/
+type Store interface {
+	GetFlow(tag string) (*Flow, error)
+	SetFlow(flow *Flow) error
+	DeleteFlow(tag string) error
+	ListFlows() ([]*Flow, error)
+	GetInstance(id string) (*Instance, error)
+	SetInstance(instance *Instance, revision int64, changes *Changes) error
+	DeleteInstance(id string) error
+	ListInstances(query Query) ([]*Instance, error)
+	GetResults(instanceId string) ([]*Result, error)
+	GetHistory(instanceId string) ([]*HistoryEntry, error)
+}
+
+var (
+	store    Store = &hooksStore{}
+	storeSet bool
+	storeMu  sync.RWMutex
+)
+
+/**
+* SetStore
+* Reemplaza la persistencia de flujos e instancias, nil vuelve a las funciones OnGet, OnSet, OnDelete...
+* @param s Store
+* @return void
+**/
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	storeSet = s != nil
+	if s == nil {
+		s = &hooksStore{}
+	}
+
+	store = s
+}
+
+/**
+* getStore
+* @return Store
+**/
+func getStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+
+	return store
+}
+
+/**
+* useHooks
+* Las funciones On* solo se usan si no se definio un store con SetStore
+**/
+func useHooks() {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+
+	if storeSet {
+		logs.Alertf(MSG_STORE_HOOKS_IGNORED)
+	}
+}
+
+/**
+* hooksStore
+* Adapta las funciones OnGet, OnSet, OnDelete, OnList, OnGetFlow, OnSetFlow y OnDeleteFlow al Store,
+* los resultados y el historial viajan con la instancia que recibe OnSet
+**/
+type hooksStore struct{}
+
+/**
+* GetFlow
+* @param tag string
+* @return *Flow, error
+**/
+func (s *hooksStore) GetFlow(tag string) (*Flow, error) {
+	if getFlow == nil {
+		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+
+	return getFlow(tag)
+}
+
+/**
+* SetFlow
+* @param flow *Flow
+* @return error
+**/
+func (s *hooksStore) SetFlow(flow *Flow) error {
+	if setFlow == nil {
+		return nil
+	}
+
+	return setFlow(flow)
+}
+
+/**
+* DeleteFlow
+* @param tag string
+* @return error
+**/
+func (s *hooksStore) DeleteFlow(tag string) error {
+	if deleteFlow == nil {
+		return nil
+	}
+
+	return deleteFlow(tag)
+}
+
+/**
+* ListFlows
+* @return []*Flow, error
+**/
+func (s *hooksStore) ListFlows() ([]*Flow, error) {
+	return nil, fmt.Errorf(MSG_STORE_UNSUPPORTED, "ListFlows")
+}
+
+/**
+* GetInstance
+* @param id string
+* @return *Instance, error
+**/
+func (s *hooksStore) GetInstance(id string) (*Instance, error) {
+	if getFn == nil {
+		return nil, errorInstanceNotFound
+	}
+
+	return getFn(id)
+}
+
+/**
+* SetInstance
+* La funcion OnSet recibe la instancia con Revision = revision+1 y es responsable de comparar
+* la revision almacenada con revision (instance.Revision-1), si no coincide retorna *ConflictError
+* @param instance *Instance, revision int64, changes *Changes
+* @return error
+**/
+func (s *hooksStore) SetInstance(instance *Instance, revision int64, changes *Changes) error {
+	n := len(instance.History)
+	instance.History = append(instance.History, changes.History...)
+	if setFn != nil {
+		err := setFn(instance)
+		if err != nil {
+			instance.History = instance.History[:n]
+			return err
+		}
+	}
+
+	for _, entry := range changes.History {
+		s.publish(entry)
+	}
+
+	return nil
+}
+
+/**
+* DeleteInstance
+* @param id string
+* @return error
+**/
+func (s *hooksStore) DeleteInstance(id string) error {
+	if deleteFn == nil {
+		return nil
+	}
+
+	return deleteFn(id)
+}
+
+/**
+* ListInstances
+* @param query Query
+* @return []*Instance, error
+**/
+func (s *hooksStore) ListInstances(query Query) ([]*Instance, error) {
+	if listFn == nil {
+		return nil, fmt.Errorf(MSG_STORE_UNSUPPORTED, "ListInstances")
+	}
+
+	return listFn(query)
+}
+
+/**
+* GetResults
+* @param instanceId string
+* @return []*Result, error
+**/
+func (s *hooksStore) GetResults(instanceId string) ([]*Result, error) {
+	return nil, fmt.Errorf(MSG_STORE_UNSUPPORTED, "GetResults")
+}
+
+/**
+* publish
+* El historial tambien se publica en EVENT_WORKFLOW_HISTORY
+* @param entry *HistoryEntry
+**/
+func (s *hooksStore) publish(entry *HistoryEntry) {
+	event.Publish(EVENT_WORKFLOW_HISTORY, et.Json{
+		"instance_id": entry.InstanceId,
+		"type":        entry.Type,
+		"step":        entry.Step,
+		"status":      entry.Status,
+		"actor":       entry.Actor,
+		"message":     entry.Message,
+		"created_at":  entry.CreatedAt,
+	})
+}
+
+/**
+* GetHistory
+* Se lee de la instancia que retorna OnGet
+* @param instanceId string
+* @return []*HistoryEntry, error
+**/
+func (s *hooksStore) GetHistory(instanceId string) ([]*HistoryEntry, error) {
+	instance, err := s.GetInstance(instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.History, nil
+}
+
+/**
+* MemoryStore
+* Implementacion de referencia, las instancias se guardan serializadas como en un backend real
+* y los flujos por referencia porque pueden tener funciones Go sin registrar
+**/
+type MemoryStore struct {
+	flows     map[string]*Flow
+	instances map[string][]byte
+	revisions map[string]int64
+	results   map[string][]*Result
+	history   map[string][]*HistoryEntry
+	mu        sync.RWMutex
+}
+
+/**
+* NewMemoryStore
+* @return *MemoryStore
+**/
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		flows:     make(map[string]*Flow),
+		instances: make(map[string][]byte),
+		revisions: make(map[string]int64),
+		results:   make(map[string][]*Result),
+		history:   make(map[string][]*HistoryEntry),
+		mu:        sync.RWMutex{},
+	}
+}
+
+/**
+* GetFlow
+* @param tag string
+* @return *Flow, error
+**/
+func (s *MemoryStore) GetFlow(tag string) (*Flow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, ok := s.flows[tag]
+	if !ok {
+		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
+	}
+
+	return result, nil
+}
+
+/**
+* SetFlow
+* @param flow *Flow
+* @return error
+**/
+func (s *MemoryStore) SetFlow(flow *Flow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flows[flow.Tag] = flow
+	return nil
+}
+
+/**
+* DeleteFlow
+* @param tag string
+* @return error
+**/
+func (s *MemoryStore) DeleteFlow(tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.flows, tag)
+	return nil
+}
+
+/**
+* ListFlows
+* @return []*Flow, error
+**/
+func (s *MemoryStore) ListFlows() ([]*Flow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Flow, 0, len(s.flows))
+	for _, flow := range s.flows {
+		result = append(result, flow)
+	}
+
+	return result, nil
+}
+
+/**
+* GetInstance
+* @param id string
+* @return *Instance, error
+**/
+func (s *MemoryStore) GetInstance(id string) (*Instance, error) {
+	s.mu.RLock()
+	bt, ok := s.instances[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errorInstanceNotFound
+	}
+
+	var result *Instance
+	err := json.Unmarshal(bt, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/**
+* SetInstance
+* @param instance *Instance, revision int64, changes *Changes
+* @return error
+**/
+func (s *MemoryStore) SetInstance(instance *Instance, revision int64, changes *Changes) error {
+	bt, err := instance.Serialize()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.revisions[instance.Id]
+	if current != revision {
+		return &ConflictError{InstanceId: instance.Id, Expected: revision, Current: current}
+	}
+
+	s.instances[instance.Id] = bt
+	s.revisions[instance.Id] = instance.Revision
+	s.results[instance.Id] = append(s.results[instance.Id], changes.Results...)
+	s.history[instance.Id] = append(s.history[instance.Id], changes.History...)
+	return nil
+}
+
+/**
+* DeleteInstance
+* @param id string
+* @return error
+**/
+func (s *MemoryStore) DeleteInstance(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.instances, id)
+	delete(s.revisions, id)
+	delete(s.results, id)
+	delete(s.history, id)
+	return nil
+}
+
+/**
+* ListInstances
+* @param query Query
+* @return []*Instance, error
+**/
+func (s *MemoryStore) ListInstances(query Query) ([]*Instance, error) {
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.instances))
+	for id := range s.instances {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+
+	slices.Sort(ids)
+	result := make([]*Instance, 0)
+	for _, id := range ids {
+		instance, err := s.GetInstance(id)
+		if err != nil {
+			continue
+		}
+
+		if !query.match(instance) {
+			continue
+		}
+
+		result = append(result, instance)
+		if query.Limit > 0 && len(result) >= query.Limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+/**
+* GetResults
+* @param instanceId string
+* @return []*Result, error
+**/
+func (s *MemoryStore) GetResults(instanceId string) ([]*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.results[instanceId]), nil
+}
+
+/**
+* GetHistory
+* @param instanceId string
+* @return []*HistoryEntry, error
+**/
+func (s *MemoryStore) GetHistory(instanceId string) ([]*HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.history[instanceId]), nil
+}
@@ -0,0 +1,248 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+/**
+* testInstance
+* @param id, tag string, status FlowStatus
+* @return *Instance
+**/
+func testInstance(id, tag string, status FlowStatus) *Instance {
+	now := time.Now()
+	return &Instance{
+		Id:        id,
+		Tag:       tag,
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+/**
+* save
+* @param store Store, instance *Instance
+* @return error
+**/
+func save(store Store, instance *Instance) error {
+	return saveChanges(store, instance, &Changes{})
+}
+
+/**
+* saveChanges
+* Guarda como Instance.Save: incrementa la revision y la restaura si falla
+* @param store Store, instance *Instance, changes *Changes
+* @return error
+**/
+func saveChanges(store Store, instance *Instance, changes *Changes) error {
+	revision := instance.Revision
+	instance.Revision++
+	err := store.SetInstance(instance, revision, changes)
+	if err != nil {
+		instance.Revision = revision
+	}
+
+	return err
+}
+
+/**
+* testStoreRevision
+* Comportamiento compare-and-set comun a todos los stores
+* @param t *testing.T, store Store
+**/
+func testStoreRevision(t *testing.T, store Store) {
+	t.Helper()
+
+	instance := testInstance("cas", "orders", FlowStatusPending)
+	if err := save(store, instance); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+
+	stale := testInstance("cas", "orders", FlowStatusPending)
+	err := save(store, stale)
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("insert over existing instance: expected ConflictError, got %v", err)
+	}
+	if conflict.Expected != 0 || conflict.Current != 1 {
+		t.Fatalf("conflict expected:0 current:1, got expected:%d current:%d", conflict.Expected, conflict.Current)
+	}
+
+	instance.Status = FlowStatusRunning
+	if err := save(store, instance); err != nil {
+		t.Fatalf("second save: %v", err)
+	}
+
+	stale.Revision = 1
+	err = save(store, stale)
+	if !errors.As(err, &conflict) {
+		t.Fatalf("stale revision: expected ConflictError, got %v", err)
+	}
+	if stale.Revision != 1 {
+		t.Fatalf("revision must be restored after a conflict, got %d", stale.Revision)
+	}
+
+	missing := testInstance("missing", "orders", FlowStatusPending)
+	missing.Revision = 3
+	err = save(store, missing)
+	if !errors.As(err, &conflict) {
+		t.Fatalf("revision on missing instance: expected ConflictError, got %v", err)
+	}
+
+	result, err := store.GetInstance("cas")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if result.Revision != 2 || result.Status != FlowStatusRunning {
+		t.Fatalf("expected revision:2 status:running, got revision:%d status:%s", result.Revision, result.Status)
+	}
+
+	if err := store.DeleteInstance("cas"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.GetInstance("cas"); err == nil {
+		t.Fatal("instance must not exist after delete")
+	}
+
+	again := testInstance("cas", "orders", FlowStatusPending)
+	if err := save(store, again); err != nil {
+		t.Fatalf("save after delete starts at revision 0: %v", err)
+	}
+}
+
+/**
+* testStoreList
+* @param t *testing.T, store Store
+**/
+func testStoreList(t *testing.T, store Store) {
+	t.Helper()
+
+	for _, instance := range []*Instance{
+		testInstance("a", "orders", FlowStatusPending),
+		testInstance("b", "orders", FlowStatusWaiting),
+		testInstance("c", "orders", FlowStatusDone),
+		testInstance("d", "payments", FlowStatusPending),
+	} {
+		if err := save(store, instance); err != nil {
+			t.Fatalf("save %s: %v", instance.Id, err)
+		}
+	}
+
+	result, err := store.ListInstances(Query{
+		Tag:    "orders",
+		Status: []FlowStatus{FlowStatusPending, FlowStatusWaiting},
+	})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+
+	ids := make(map[string]bool)
+	for _, instance := range result {
+		ids[instance.Id] = true
+	}
+	if len(ids) != 2 || !ids["a"] || !ids["b"] {
+		t.Fatalf("expected a and b, got %v", ids)
+	}
+
+	result, err = store.ListInstances(Query{Limit: 1})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("limit 1, got %d", len(result))
+	}
+}
+
+/**
+* testStoreHistory
+* Los resultados y el historial se escriben con la instancia, con un conflicto no se escribe nada
+* @param t *testing.T, store Store
+**/
+func testStoreHistory(t *testing.T, store Store) {
+	t.Helper()
+
+	now := time.Now()
+	instance := testInstance("history", "orders", FlowStatusRunning)
+	stale := testInstance("history", "orders", FlowStatusRunning)
+	types := []TpHistory{HistoryCreated, HistoryStepStarted, HistoryStepCompleted}
+	for i, tp := range types {
+		err := saveChanges(store, instance, &Changes{
+			Results: []*Result{{Step: i}},
+			History: []*HistoryEntry{{
+				InstanceId: "history",
+				Type:       tp,
+				Step:       i,
+				CreatedAt:  now.Add(time.Duration(i) * time.Millisecond),
+			}},
+		})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	err := saveChanges(store, stale, &Changes{
+		Results: []*Result{{Step: 9}},
+		History: []*HistoryEntry{{InstanceId: "history", Type: HistoryStop, CreatedAt: now.Add(time.Second)}},
+	})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("stale save: expected ConflictError, got %v", err)
+	}
+
+	results, err := store.GetResults("history")
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if len(results) != len(types) {
+		t.Fatalf("expected %d results, got %d", len(types), len(results))
+	}
+
+	result, err := store.GetHistory("history")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(result) != len(types) {
+		t.Fatalf("expected %d entries, got %d", len(types), len(result))
+	}
+	for i, entry := range result {
+		if entry.Type != types[i] {
+			t.Fatalf("entry %d: expected %s, got %s", i, types[i], entry.Type)
+		}
+	}
+}
+
+func TestMemoryStoreRevision(t *testing.T) {
+	testStoreRevision(t, NewMemoryStore())
+}
+
+func TestMemoryStoreList(t *testing.T) {
+	testStoreList(t, NewMemoryStore())
+}
+
+func TestMemoryStoreHistory(t *testing.T) {
+	testStoreHistory(t, NewMemoryStore())
+}
+
+func TestSetStoreKeepsStoreOverHooks(t *testing.T) {
+	memory := NewMemoryStore()
+	SetStore(memory)
+	t.Cleanup(func() {
+		SetStore(nil)
+		getFn = nil
+	})
+
+	OnGet(func(id string) (*Instance, error) {
+		return nil, errorInstanceNotFound
+	})
+	if getStore() != memory {
+		t.Fatal("an On* function must not replace a store set with SetStore")
+	}
+
+	SetStore(nil)
+	if _, ok := getStore().(*hooksStore); !ok {
+		t.Fatalf("SetStore(nil) must go back to the On* functions, got %T", getStore())
+	}
+}
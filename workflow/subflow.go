@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* newStepSubFlow
+* @param name, description, tag string, mapping map[string]string, stop bool
+* @return *Step
+**/
+func newStepSubFlow(name, description, tag string, mapping map[string]string, stop bool) (*Step, error) {
+	result := &Step{
+		Name:        name,
+		Description: description,
+		Type:        TpSubFlow,
+		Stop:        stop,
+		FlowTag:     tag,
+		Mapping:     mapping,
+	}
+	result.fn = result.runSubFlow
+
+	return result, nil
+}
+
+/**
+* childCtx
+* Sin mapping el hijo recibe una copia del ctx, con mapping cada llave toma el valor de la ruta indicada
+* @param ctx et.Json
+* @return et.Json
+**/
+func (s *Step) childCtx(ctx et.Json) et.Json {
+	if len(s.Mapping) == 0 {
+		return ctx.Clone()
+	}
+
+	result := et.Json{}
+	for key, path := range s.Mapping {
+		val, ok := getPath(ctx, path)
+		if ok {
+			result[key] = val
+		}
+	}
+
+	return result
+}
+
+/**
+* runSubFlow
+* El hijo se crea y ejecuta la primera vez con su bloqueo y lease, si no termina (en espera o
+* con reintentos de resilencia pendientes) el padre queda en espera hasta que el hijo finalice
+* y lo reanude. Al terminar el hijo se quita de memoria
+* @param flow *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *Step) runSubFlow(flow *Instance, ctx et.Json) (et.Json, error) {
+	if flow.workFlows == nil {
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_WORKFLOWS_IS_NIL)
+	}
+
+	childId := fmt.Sprintf("%s:%d", flow.Id, flow.Current)
+	child, exists := flow.workFlows.loadInstance(childId)
+	if !exists {
+		var err error
+		child, err = flow.workFlows.newInstance(s.FlowTag, childId, flow.Tags, 0, flow.UpdatedBy)
+		if err != nil {
+			return et.Json{}, err
+		}
+
+		unlock, err := flow.workFlows.acquire(child)
+		if err != nil {
+			return et.Json{}, err
+		}
+
+		child.ParentId = flow.Id
+		flow.addChild(childId)
+		release := child.setContext(flow.Context())
+		_, err = child.run(s.childCtx(ctx), flow.UpdatedBy)
+		release()
+		unlock()
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			flow.workFlows.Remove(childId)
+			return et.Json{}, err
+		}
+	}
+
+	if child.Status == FlowStatusDone {
+		flow.workFlows.Remove(childId)
+		return child.Ctx.Clone(), nil
+	}
+
+	if child.isFailed() {
+		flow.workFlows.Remove(childId)
+		reason := string(child.Status)
+		if child.err != nil {
+			reason = child.err.Error()
+		}
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_SUBFLOW_FAILED, childId, s.FlowTag, reason)
+	}
+
+	flow.suspend()
+	return ctx, nil
+}
+
+/**
+* addChild
+* @param id string
+**/
+func (s *Instance) addChild(id string) {
+	if slices.Contains(s.Children, id) {
+		return
+	}
+
+	s.Children = append(s.Children, id)
+}
+
+/**
+* isFailed
+* Fallo definitivo o cancelada, sin reintentos pendientes de resilencia
+* @return bool
+**/
+func (s *Instance) isFailed() bool {
+	if s.Status == FlowStatusRolledBack || s.Status == FlowStatusCompensationFailed || s.Status == FlowStatusCancelled {
+		return true
+	}
+
+	if s.Status != FlowStatusFailed {
+		return false
+	}
+
+	return s.TotalAttempts == 0 || s.done
+}
+
+/**
+* Tree
+* @return et.Json
+**/
+func (s *Instance) Tree() et.Json {
+	result := s.ToJson()
+	children := make([]et.Json, 0)
+	for _, id := range s.Children {
+		if s.workFlows == nil {
+			break
+		}
+
+		child, exists := s.workFlows.loadInstance(id)
+		if !exists {
+			continue
+		}
+
+		children = append(children, child.Tree())
+	}
+	result["children"] = children
+
+	return result
+}
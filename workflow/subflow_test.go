@@ -0,0 +1,58 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+func TestSubFlowDoneReturnsChildCtx(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	wf.newFlowFn("child_ok", "v1", "Child", "", func(flow *Instance, ctx et.Json) (et.Json, error) {
+		return et.Json{"paid": ctx.Int("amount")}, nil
+	}, false, "test")
+	wf.newFlowFn("parent_ok", "v1", "Parent", "", testPass, false, "test").
+		SubFlow("Pay", "", "child_ok", map[string]string{"amount": "order.total"}, false)
+
+	ctx := testVisited(t, "parent-ok", "parent_ok", et.Json{"order": et.Json{"total": 30}})
+	if ctx.Int("paid") != 30 {
+		t.Fatalf("the child ctx must be the step result, ctx %v", ctx)
+	}
+
+	child := waitStatus(t, "parent-ok:1", FlowStatusDone)
+	if child.ParentId != "parent-ok" {
+		t.Fatalf("expected parent parent-ok, got %q", child.ParentId)
+	}
+}
+
+func TestSubFlowFailureRollsBackParent(t *testing.T) {
+	wf := testWorkFlows(t, NewMemoryStore())
+	compensated := make(chan string, 2)
+	wf.newFlowFn("child_fail", "v1", "Child", "", testPass, false, "test").
+		Rollback(func(flow *Instance, ctx et.Json) (et.Json, error) {
+			compensated <- "child"
+			return ctx, nil
+		}).
+		StepFn("Charge", "", testFail, false)
+	wf.newFlowFn("parent_fail", "v1", "Parent", "", testStep("reserved", true), false, "test").
+		Rollback(func(flow *Instance, ctx et.Json) (et.Json, error) {
+			compensated <- "parent"
+			return ctx, nil
+		}).
+		SubFlow("Pay", "", "child_fail", nil, false).
+		StepFn("End", "", testStep("end", true), false)
+
+	_, err := Run("parent-fail", "parent_fail", 0, et.Json{}, et.Json{}, "test")
+	if err == nil {
+		t.Fatal("the child failure must fail the parent")
+	}
+
+	waitStatus(t, "parent-fail:1", FlowStatusRolledBack)
+	parent := waitStatus(t, "parent-fail", FlowStatusRolledBack)
+	if parent.Ctx["end"] != nil {
+		t.Fatal("the parent must not continue after the failed child")
+	}
+	if first, second := <-compensated, <-compensated; first != "child" || second != "parent" {
+		t.Fatalf("the child compensates first and then the parent, got %s %s", first, second)
+	}
+}
@@ -0,0 +1,410 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/dop251/goja"
+)
+
+type TpSeverity string
+
+const (
+	SeverityError   TpSeverity = "error"
+	SeverityWarning TpSeverity = "warning"
+)
+
+type Problem struct {
+	Step     int        `json:"step"`
+	Name     string     `json:"name"`
+	Severity TpSeverity `json:"severity"`
+	Code     string     `json:"code"`
+	Message  string     `json:"message"`
+}
+
+type Problems []*Problem
+
+/**
+* Error
+* @return string
+**/
+func (s Problems) Error() string {
+	result := make([]string, 0, len(s))
+	for _, problem := range s {
+		result = append(result, fmt.Sprintf("step:%d name:%s %s", problem.Step, problem.Name, problem.Message))
+	}
+
+	return strings.Join(result, "; ")
+}
+
+/**
+* Errors
+* @return Problems
+**/
+func (s Problems) Errors() Problems {
+	result := make(Problems, 0)
+	for _, problem := range s {
+		if problem.Severity == SeverityError {
+			result = append(result, problem)
+		}
+	}
+
+	return result
+}
+
+type validator struct {
+	flow     *Flow
+	problems Problems
+}
+
+/**
+* add
+* @param idx int, step *Step, severity TpSeverity, code, format string, args ...any
+**/
+func (s *validator) add(idx int, step *Step, severity TpSeverity, code, format string, args ...any) {
+	name := ""
+	if step != nil {
+		name = step.Name
+	}
+
+	s.problems = append(s.problems, &Problem{
+		Step:     idx,
+		Name:     name,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+/**
+* goTo
+* -1 solo es valido cuando allowNext, significa continuar al siguiente step
+* @param idx int, step *Step, target int, allowNext bool
+**/
+func (s *validator) goTo(idx int, step *Step, target int, allowNext bool) {
+	if allowNext && target == -1 {
+		return
+	}
+
+	if target < 0 || target >= len(s.flow.Steps) {
+		s.add(idx, step, SeverityError, "goto_out_of_range", MSG_VALIDATE_GOTO_OUT_OF_RANGE, target, len(s.flow.Steps))
+	}
+}
+
+/**
+* name
+* @param idx int, step *Step, name string
+**/
+func (s *validator) name(idx int, step *Step, name string) {
+	if name != "" && s.flow.IndexOf(name) == -1 {
+		s.add(idx, step, SeverityError, "step_not_found", MSG_STEP_NOT_FOUND, name, s.flow.Tag)
+	}
+}
+
+/**
+* expression
+* @param idx int, step *Step, expression string
+**/
+func (s *validator) expression(idx int, step *Step, expression string) {
+	if expression == "" {
+		return
+	}
+
+	_, err := govaluate.NewEvaluableExpression(expression)
+	if err != nil {
+		s.add(idx, step, SeverityError, "invalid_expression", MSG_INSTANCE_EVALUATE, expression, err.Error())
+	}
+}
+
+/**
+* executable
+* Valida los steps que ejecutan codigo: definitions, functions, branches y body
+* @param idx int, step *Step
+**/
+func (s *validator) executable(idx int, step *Step) {
+	switch step.Type {
+	case TpDefinition:
+		if strings.TrimSpace(step.Definition) == "" {
+			s.add(idx, step, SeverityError, "empty_definition", MSG_INSTANCE_DEFINITION_EMPTY)
+			return
+		}
+
+		_, err := goja.Compile(step.Name, step.Definition, false)
+		if err != nil {
+			s.add(idx, step, SeverityError, "invalid_definition", MSG_VALIDATE_COMPILE, err.Error())
+		}
+	case TpFn:
+		if step.fn == nil {
+			s.add(idx, step, SeverityError, "empty_function", MSG_VALIDATE_FUNCTION_NIL)
+		}
+	case TpParallel:
+		if len(step.Branches) == 0 {
+			s.add(idx, step, SeverityError, "empty_parallel", MSG_INSTANCE_PARALLEL_EMPTY, step.Name)
+		}
+		for _, branch := range step.Branches {
+			s.executable(idx, branch)
+			s.rollback(idx, branch)
+			s.branch(idx, branch)
+		}
+	case TpForEach:
+		if step.Path == "" {
+			s.add(idx, step, SeverityError, "empty_path", MSG_VALIDATE_REQUIRED, "path")
+		}
+		if len(step.Body) == 0 {
+			s.add(idx, step, SeverityError, "empty_body", MSG_INSTANCE_FOREACH_EMPTY, step.Name)
+		}
+		for _, body := range step.Body {
+			s.executable(idx, body)
+		}
+	case TpSubFlow:
+		if step.FlowTag == "" {
+			s.add(idx, step, SeverityError, "empty_flow_tag", MSG_VALIDATE_REQUIRED, "flow_tag")
+		}
+	case TpSignal:
+		if step.Signal == "" {
+			s.add(idx, step, SeverityError, "empty_signal", MSG_VALIDATE_REQUIRED, "signal")
+		}
+	case TpSleep:
+		if step.Until == "" && step.Duration <= 0 {
+			s.add(idx, step, SeverityError, "empty_sleep", MSG_VALIDATE_REQUIRED, "duration")
+		}
+	default:
+		s.add(idx, step, SeverityError, "invalid_type", MSG_VALIDATE_INVALID_TYPE, step.Type)
+	}
+}
+
+/**
+* branch
+* Un branch solo admite Timeout, retry, input, output y on_error son del step parallel
+* @param idx int, branch *Step
+**/
+func (s *validator) branch(idx int, branch *Step) {
+	if branch.Timeout < 0 {
+		s.add(idx, branch, SeverityError, "invalid_timeout", MSG_VALIDATE_INVALID_TIMEOUT, branch.Timeout)
+	}
+
+	options := map[string]bool{
+		"retry":    branch.Retry != nil,
+		"input":    len(branch.Input) > 0,
+		"output":   len(branch.Output) > 0,
+		"on_error": len(branch.OnError) > 0,
+	}
+	for _, option := range []string{"retry", "input", "output", "on_error"} {
+		if options[option] {
+			s.add(idx, branch, SeverityError, "invalid_branch", MSG_VALIDATE_BRANCH_OPTION, branch.Name, option)
+		}
+	}
+}
+
+/**
+* rollback
+* @param idx int, step *Step
+**/
+func (s *validator) rollback(idx int, step *Step) {
+	if step.RollbackFunction != "" && step.rollbacks == nil {
+		s.add(idx, step, SeverityError, "empty_rollback_function", MSG_VALIDATE_FUNCTION_NIL)
+	}
+
+	if step.RollbackDefinition == "" {
+		return
+	}
+
+	_, err := goja.Compile(step.Name, step.RollbackDefinition, false)
+	if err != nil {
+		s.add(idx, step, SeverityError, "invalid_rollback_definition", MSG_VALIDATE_COMPILE, err.Error())
+	}
+}
+
+/**
+* next
+* Steps a los que se puede llegar desde el step idx, sin contar los Goto de usuario,
+* despues del goto de un IfElse por indice se avanza al step siguiente del destino
+* @param idx int, step *Step
+* @return []int
+**/
+func (s *validator) next(idx int, step *Step) []int {
+	result := make([]int, 0)
+	if step.Expression != "" {
+		yes, no := step.YesGoTo, step.NoGoTo
+		if !step.goToByName(true) {
+			yes++
+		}
+		if !step.goToByName(false) {
+			no++
+		}
+
+		return append(result, yes, no)
+	}
+
+	if step.Switch != "" {
+		for _, target := range step.Cases {
+			result = append(result, target)
+		}
+		if step.DefaultGoTo != -1 {
+			return append(result, step.DefaultGoTo)
+		}
+	}
+
+	if step.Type == TpSignal && step.TimeoutGoTo != -1 {
+		result = append(result, step.TimeoutGoTo)
+	}
+
+	for _, handler := range step.OnError {
+		result = append(result, handler.GoTo)
+	}
+
+	return append(result, idx+1)
+}
+
+/**
+* reachable
+* @return void
+**/
+func (s *validator) reachable() {
+	n := len(s.flow.Steps)
+	visited := make([]bool, n)
+	pending := []int{0}
+	for len(pending) > 0 {
+		idx := pending[0]
+		pending = pending[1:]
+		if idx < 0 || idx >= n || visited[idx] {
+			continue
+		}
+
+		visited[idx] = true
+		pending = append(pending, s.next(idx, s.flow.Steps[idx])...)
+	}
+
+	for idx, ok := range visited {
+		if !ok {
+			step := s.flow.Steps[idx]
+			s.add(idx, step, SeverityWarning, "unreachable", MSG_VALIDATE_UNREACHABLE)
+		}
+	}
+}
+
+/**
+* Validate
+* Valida la definicion del flujo, los problemas con severidad error impiden registrarlo
+* @return Problems
+**/
+func (s *Flow) Validate() Problems {
+	result := &validator{
+		flow:     s,
+		problems: make(Problems, 0),
+	}
+
+	if len(s.Steps) == 0 {
+		result.add(-1, nil, SeverityError, "empty_flow", MSG_VALIDATE_EMPTY_FLOW, s.Tag)
+		return result.problems
+	}
+
+	names := make(map[string]bool)
+	for idx, step := range s.Steps {
+		if step == nil {
+			result.add(idx, nil, SeverityError, "nil_step", MSG_VALIDATE_NIL_STEP)
+			continue
+		}
+
+		if step.Name == "" {
+			result.add(idx, step, SeverityError, "empty_name", MSG_VALIDATE_REQUIRED, "name")
+		} else if names[step.Name] {
+			result.add(idx, step, SeverityError, "duplicated_name", MSG_STEP_DUPLICATED, step.Name, s.Tag)
+		}
+		names[step.Name] = true
+
+		if step.Timeout < 0 {
+			result.add(idx, step, SeverityError, "invalid_timeout", MSG_VALIDATE_INVALID_TIMEOUT, step.Timeout)
+		}
+
+		if step.Retry != nil && step.Retry.MaxAttempts < 1 {
+			result.add(idx, step, SeverityError, "invalid_retry", MSG_VALIDATE_REQUIRED, "retry.max_attempts")
+		}
+
+		for _, handler := range step.OnError {
+			if handler.GoToStep == "" && handler.GoTo == -1 {
+				result.add(idx, step, SeverityError, "on_error_without_target", MSG_ON_ERROR_WITHOUT_TARGET, step.Name, s.Tag)
+			}
+			result.name(idx, step, handler.GoToStep)
+			if handler.Pattern == "" {
+				continue
+			}
+
+			_, err := regexp.Compile(handler.Pattern)
+			if err != nil {
+				result.add(idx, step, SeverityError, "invalid_pattern", MSG_VALIDATE_COMPILE, err.Error())
+			}
+		}
+
+		for dest := range step.Output {
+			if strings.TrimPrefix(dest, "ctx.") == "" || strings.Contains(dest, "..") {
+				result.add(idx, step, SeverityError, "invalid_output", MSG_VALIDATE_REQUIRED, "output")
+			}
+		}
+
+		result.executable(idx, step)
+		result.rollback(idx, step)
+		result.expression(idx, step, step.Expression)
+		result.expression(idx, step, step.Switch)
+		result.expression(idx, step, step.Until)
+		result.name(idx, step, step.YesGoToStep)
+		result.name(idx, step, step.NoGoToStep)
+		result.name(idx, step, step.DefaultGoToStep)
+		result.name(idx, step, step.TimeoutGoToStep)
+		for _, name := range step.CasesStep {
+			result.name(idx, step, name)
+		}
+	}
+
+	if len(result.problems.Errors()) > 0 {
+		return result.problems
+	}
+
+	err := s.prepare()
+	if err != nil {
+		result.add(-1, nil, SeverityError, "unresolved", "%s", err.Error())
+		return result.problems
+	}
+
+	for idx, step := range s.Steps {
+		if step.Expression != "" {
+			result.goTo(idx, step, step.YesGoTo, false)
+			result.goTo(idx, step, step.NoGoTo, false)
+		}
+
+		if step.Switch != "" {
+			for _, target := range step.Cases {
+				result.goTo(idx, step, target, false)
+			}
+			result.goTo(idx, step, step.DefaultGoTo, true)
+		}
+
+		if step.Type == TpSignal {
+			result.goTo(idx, step, step.TimeoutGoTo, true)
+		}
+
+		for _, handler := range step.OnError {
+			result.goTo(idx, step, handler.GoTo, false)
+		}
+	}
+
+	result.reachable()
+
+	return result.problems
+}
+
+/**
+* check
+* Retorna los problemas de severidad error como error
+* @return error
+**/
+func (s *Flow) check() error {
+	problems := s.Validate().Errors()
+	if len(problems) > 0 {
+		return problems
+	}
+
+	return nil
+}
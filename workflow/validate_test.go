@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+/**
+* hasProblem
+* @param problems Problems, code string
+* @return bool
+**/
+func hasProblem(problems Problems, code string) bool {
+	for _, problem := range problems {
+		if problem.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestValidateEmpty(t *testing.T) {
+	flow := newFlow("validate_empty", "1.0.0", "Empty", "Empty", "test")
+	if problems := flow.Validate(); !hasProblem(problems, "empty_flow") {
+		t.Fatalf("expected empty_flow, got %s", problems)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	flow := newFlow("validate_problems", "1.0.0", "Problems", "Problems", "test").
+		Step("Start", "Inicio", "ctx", false).
+		Step("Start", "Duplicado", "ctx +", false).
+		Step("Route", "Ruta", "ctx", false).
+		IfElse("total >", 0, 1)
+	flow.Steps = append(flow.Steps, &Step{Name: "Unknown", Type: "queue"})
+
+	problems := flow.Validate()
+	for _, code := range []string{"duplicated_name", "invalid_definition", "invalid_expression", "invalid_type"} {
+		if !hasProblem(problems, code) {
+			t.Fatalf("expected %s, got %s", code, problems)
+		}
+	}
+	if flow.check() == nil {
+		t.Fatal("check must fail with error problems")
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	flow := newFlow("validate_targets", "1.0.0", "Targets", "Targets", "test").
+		Step("Start", "Inicio", "ctx", false).
+		IfElseByName("total > 10", "Missing", "Start")
+	if problems := flow.Validate(); !hasProblem(problems, "step_not_found") {
+		t.Fatalf("expected step_not_found, got %s", problems)
+	}
+
+	flow = newFlow("validate_range", "1.0.0", "Range", "Range", "test").
+		Step("Start", "Inicio", "ctx", false).
+		IfElse("total > 10", 0, 5)
+	if problems := flow.Validate(); !hasProblem(problems, "goto_out_of_range") {
+		t.Fatalf("expected goto_out_of_range, got %s", problems)
+	}
+
+	flow = newFlow("validate_on_error", "1.0.0", "OnError", "OnError", "test").
+		Step("Start", "Inicio", "ctx", false)
+	flow.Steps[0].OnError = append(flow.Steps[0].OnError, &OnError{Code: "x", GoTo: -1})
+	if problems := flow.Validate(); !hasProblem(problems, "on_error_without_target") {
+		t.Fatalf("expected on_error_without_target, got %s", problems)
+	}
+}
+
+func TestValidateReachable(t *testing.T) {
+	// Despues del goto de un IfElse se continua en el step siguiente al destino
+	flow := newFlow("validate_reachable", "1.0.0", "Reachable", "Reachable", "test").
+		Step("Start", "Inicio", "ctx", false).
+		IfElse("total > 10", 1, 2).
+		Step("Skipped", "Nunca", "ctx", false).
+		Step("Big", "Grande", "ctx", false).
+		Step("Small", "Pequeño", "ctx", false)
+
+	problems := flow.Validate()
+	if len(problems.Errors()) > 0 {
+		t.Fatalf("flow must be valid, got %s", problems)
+	}
+
+	for _, problem := range problems {
+		if problem.Code == "unreachable" && problem.Name != "Skipped" {
+			t.Fatalf("only Skipped is unreachable, got %s", problem.Name)
+		}
+	}
+	if !hasProblem(problems, "unreachable") {
+		t.Fatal("expected Skipped to be unreachable")
+	}
+}
+
+func TestValidateReachableByName(t *testing.T) {
+	// Por nombre se continua en el step indicado
+	flow := newFlow("validate_reachable_name", "1.0.0", "Reachable", "Reachable", "test").
+		Step("Start", "Inicio", "ctx", false).
+		IfElseByName("total > 10", "Big", "Small").
+		Step("Skipped", "Nunca", "ctx", false).
+		Step("Big", "Grande", "ctx", true).
+		Step("Small", "Pequeño", "ctx", false)
+
+	problems := flow.Validate()
+	for _, problem := range problems {
+		if problem.Code == "unreachable" && problem.Name != "Skipped" {
+			t.Fatalf("only Skipped is unreachable, got %s", problem.Name)
+		}
+	}
+	if !hasProblem(problems, "unreachable") {
+		t.Fatal("expected Skipped to be unreachable")
+	}
+}
+
+func TestValidateBranchOptions(t *testing.T) {
+	flow := newFlow("validate_branch", "1.0.0", "Branch", "Branch", "test").
+		Parallel("Fan", "Ramas", 0, TpMergeAll, false).
+		Branch("Mail", "Correo", "ctx")
+	flow.Steps[0].Branches[0].Retry = &Retry{MaxAttempts: 3}
+	if problems := flow.Validate(); !hasProblem(problems, "invalid_branch") {
+		t.Fatalf("expected invalid_branch, got %s", problems)
+	}
+
+	flow.Steps[0].Branches[0].Retry = nil
+	flow.Steps[0].Branches[0].Timeout = time.Second
+	if problems := flow.Validate(); hasProblem(problems, "invalid_branch") {
+		t.Fatalf("timeout is supported in a branch, got %s", problems)
+	}
+}
+
+func TestValidateInvalidTimeout(t *testing.T) {
+	flow := newFlow("validate_timeout", "1.0.0", "Timeout", "Timeout", "test").
+		Step("Start", "Inicio", "ctx", false).
+		Timeout(-time.Second)
+
+	for _, problem := range flow.Validate() {
+		if problem.Code != "invalid_timeout" {
+			continue
+		}
+
+		if expect := fmt.Sprintf(MSG_VALIDATE_INVALID_TIMEOUT, -time.Second); problem.Message != expect {
+			t.Fatalf("expected %q, got %q", expect, problem.Message)
+		}
+		return
+	}
+
+	t.Fatal("expected invalid_timeout")
+}
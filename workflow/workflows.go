@@ -1,8 +1,11 @@
 package workflow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/cgalvisleon/et/et"
 	"github.com/cgalvisleon/et/event"
@@ -15,15 +18,24 @@ import (
 
 var (
 	errorInstanceNotFound = fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
+	errorInstanceRunning  = fmt.Errorf(MSG_INSTANCE_ALREADY_RUNNING)
 )
 
-const packageName = "workflow"
+const (
+	packageName     = "workflow"
+	conflictRetries = 3
+)
 
 type instanceFn func(instanceId, tag string, startId int, tags, ctx et.Json, createdBy string) (et.Json, error)
 
 type WorkFlows struct {
 	Flows     map[string]*Flow     `json:"flows"`
 	Instances map[string]*Instance `json:"instances"`
+	timers    *scheduler           `json:"-"`
+	pool      *pool                `json:"-"`
+	lease     Lease                `json:"-"`
+	leaseTTL  time.Duration        `json:"-"`
+	owner     string               `json:"-"`
 	mu        sync.Mutex           `json:"-"`
 }
 
@@ -35,8 +47,13 @@ func newWorkFlows() *WorkFlows {
 	result := &WorkFlows{
 		Flows:     make(map[string]*Flow),
 		Instances: make(map[string]*Instance),
+		lease:     NewMemoryLease(),
+		leaseTTL:  leaseDefaultTTL,
+		owner:     fmt.Sprintf("%s:%s", workerHost, reg.ULID()),
 		mu:        sync.Mutex{},
 	}
+	result.timers = newScheduler(result)
+	result.pool = newPool(result)
 
 	return result
 }
@@ -74,6 +91,37 @@ func (s *WorkFlows) Remove(instanceId string) {
 	delete(s.Instances, instanceId)
 }
 
+/**
+* addOrGet
+* Si otra llamada ya agrego la instancia se retorna la existente
+* @param instance *Instance
+* @return *Instance, bool
+**/
+func (s *WorkFlows) addOrGet(instance *Instance) (*Instance, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.Instances[instance.Id]
+	if ok {
+		return current, true
+	}
+
+	s.Instances[instance.Id] = instance
+	return instance, false
+}
+
+/**
+* getFlowByTag
+* @param tag string
+* @return *Flow
+**/
+func (s *WorkFlows) getFlowByTag(tag string) *Flow {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Flows[tag]
+}
+
 /**
 * Count
 * @return int
@@ -95,11 +143,16 @@ func (s *WorkFlows) newInstance(tag, id string, tags et.Json, startId int, creat
 		return nil, fmt.Errorf(MSG_INSTANCE_ID_REQUIRED)
 	}
 
-	flow := s.Flows[tag]
+	flow := s.getFlowByTag(tag)
 	if flow == nil {
 		return nil, fmt.Errorf(MSG_FLOW_NOT_FOUND)
 	}
 
+	err := flow.prepare()
+	if err != nil {
+		return nil, err
+	}
+
 	if startId == -1 {
 		startId = 0
 	}
@@ -120,12 +173,17 @@ func (s *WorkFlows) newInstance(tag, id string, tags et.Json, startId int, creat
 		PinnedData: et.Json{},
 		Results:    make(map[int]*Result),
 		Rollbacks:  make(map[int]*Result),
+		Signals:    make(map[string]et.Json),
 		Tags:       tags,
 		WorkerHost: workerHost,
 		goTo:       -1,
 		vm:         vm.New(),
 	}
-	s.Add(result)
+	current, exists := s.addOrGet(result)
+	if exists {
+		return current, nil
+	}
+	result.addHistory(HistoryCreated, tag)
 	result.SetStatus(FlowStatusPending)
 
 	return result, nil
@@ -141,31 +199,100 @@ func (s *WorkFlows) loadInstance(id string) (*Instance, bool) {
 		return nil, false
 	}
 
+	s.mu.Lock()
 	result, ok := s.Instances[id]
+	s.mu.Unlock()
 	if ok {
 		return result, true
 	}
 
-	if getFn != nil {
-		result, err := getFn(id)
-		if err != nil {
-			return nil, false
-		}
+	result, err := getStore().GetInstance(id)
+	if err != nil || result == nil {
+		return nil, false
+	}
+
+	return s.bindInstance(result)
+}
+
+/**
+* bindInstance
+* Asocia una instancia leida del store con su flujo y la agrega a memoria
+* @param instance *Instance
+* @return *Instance, bool
+**/
+func (s *WorkFlows) bindInstance(instance *Instance) (*Instance, bool) {
+	flow := s.getFlowByTag(instance.Tag)
+	if flow == nil {
+		return nil, false
+	}
+
+	instance.Flow = flow
+	instance.workFlows = s
+	instance.goTo = -1
+	instance.vm = vm.New()
+	instance, _ = s.addOrGet(instance)
+
+	return instance, true
+}
+
+/**
+* restore
+* Carga en memoria las instancias pendientes o en espera del store, los flujos que no esten
+* registrados se buscan en el store, los timers se reprograman y las compensaciones en curso continuan
+* @return int, error
+**/
+func (s *WorkFlows) restore() (int, error) {
+	instances, err := getStore().ListInstances(Query{
+		Status: []FlowStatus{FlowStatusPending, FlowStatusWaiting, FlowStatusRollingBack},
+	})
+	if err != nil {
+		return 0, err
+	}
 
-		flow := s.Flows[result.Tag]
-		if flow == nil {
-			return nil, false
+	result := 0
+	rollbacks := 0
+	restored := make([]*Instance, 0, len(instances))
+	for _, instance := range instances {
+		if s.getFlowByTag(instance.Tag) == nil {
+			flow, err := getStore().GetFlow(instance.Tag)
+			if err != nil {
+				logs.Error(err)
+				continue
+			}
+
+			err = flow.check()
+			if err != nil {
+				logs.Error(err)
+				continue
+			}
+
+			s.add(flow)
 		}
 
-		result.Flow = flow
-		result.goTo = -1
-		result.vm = vm.New()
-		s.Add(result)
+		instance, ok := s.bindInstance(instance)
+		if !ok {
+			continue
+		}
 
-		return result, true
+		result++
+		restored = append(restored, instance)
+		if instance.Status == FlowStatusRollingBack {
+			rollbacks++
+			go func(instanceId string) {
+				_, err := s.rollback(instanceId)
+				if err != nil {
+					logs.Error(err)
+				}
+			}(instance.Id)
+		}
+	}
+	s.timers.restore(restored)
+	logs.Logf(packageName, MSG_INSTANCES_RESTORED, result)
+	if rollbacks > 0 {
+		logs.Logf(packageName, MSG_ROLLBACKS_RESTORED, rollbacks)
 	}
 
-	return nil, false
+	return result, nil
 }
 
 /**
@@ -190,21 +317,134 @@ func (s *WorkFlows) getOrCreateInstance(id, tag string, startId int, tags et.Jso
 * @return et.Json, error
 **/
 func (s *WorkFlows) run(instanceId, tag string, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
+	return s.runContext(context.Background(), instanceId, tag, step, tags, ctx, runBy)
+}
+
+/**
+* runContext
+* Al cancelar c se interrumpe el step en ejecucion y la instancia queda cancelada
+* @param c context.Context, instanceId, tag string, step int, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) runContext(c context.Context, instanceId, tag string, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
 	instance, err := s.getOrCreateInstance(instanceId, tag, step, tags, runBy)
 	if err != nil {
 		return et.Json{}, err
 	}
 
+	return s.runInstance(c, instance, step, tags, ctx, runBy)
+}
+
+/**
+* runInstance
+* @param c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) runInstance(c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
+	result, err := s.execute(c, instance, step, tags, ctx, runBy, "")
+	s.deliver(instance)
+
+	return result, err
+}
+
+/**
+* continueContext
+* Reanuda la instancia desde su step actual, la transicion se registra con el bloqueo tomado
+* @param c context.Context, instanceId string, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) continueContext(c context.Context, instanceId string, tags, ctx et.Json, runBy string) (et.Json, error) {
+	instance, exists := s.loadInstance(instanceId)
+	if !exists {
+		return et.Json{}, errorInstanceNotFound
+	}
+
+	result, err := s.execute(c, instance, -1, tags, ctx, runBy, HistoryContinue)
+	s.deliver(instance)
+
+	return result, err
+}
+
+/**
+* execute
+* Ejecuta la instancia con su bloqueo y lease, history se registra despues de tomar el bloqueo
+* @param c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string, history TpHistory
+* @return et.Json, error
+**/
+func (s *WorkFlows) execute(c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string, history TpHistory) (et.Json, error) {
+	unlock, err := s.acquire(instance)
+	if err != nil {
+		return et.Json{}, err
+	}
+	defer unlock()
+
+	if history != "" {
+		instance.UpdatedBy = runBy
+		instance.addHistory(history, "")
+	}
+
+	return s.runLocked(c, instance, step, tags, ctx, runBy)
+}
+
+/**
+* retry
+* Reintento de resilience sobre la instancia guardada, toma el bloqueo y el lease como execute
+* y conserva el contador de intentos de la instancia que fallo
+* @param failed *Instance, ctx et.Json
+* @return et.Json, error
+**/
+func (s *WorkFlows) retry(failed *Instance, ctx et.Json) (et.Json, error) {
+	instance, exists := s.loadInstance(failed.Id)
+	if !exists {
+		return et.Json{}, errorInstanceNotFound
+	}
+
+	unlock, err := s.acquire(instance)
+	if err != nil {
+		return et.Json{}, err
+	}
+
+	instance.resilence = failed.resilence
+	result, err := s.runLocked(context.Background(), instance, -1, et.Json{}, ctx, instance.UpdatedBy)
+	unlock()
+	s.deliver(instance)
+
+	return result, err
+}
+
+/**
+* runLocked
+* Ejecuta la instancia, quien llama debe tener su bloqueo
+* @param c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string
+* @return et.Json, error
+**/
+func (s *WorkFlows) runLocked(c context.Context, instance *Instance, step int, tags, ctx et.Json, runBy string) (et.Json, error) {
+	instanceId := instance.Id
+	release := instance.setContext(c)
+	defer release()
+
 	instance.SetTags(tags)
 	if step > 0 {
 		instance.Current = step
 	}
 	result, err := instance.run(ctx, runBy)
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		s.Remove(instanceId)
+		return et.Json{}, err
+	}
+
+	if instance.ParentId != "" {
+		s.resumeParent(instance, runBy)
+	}
+
 	if err != nil {
 		return et.Json{}, err
 	}
 
-	s.Remove(instanceId)
+	if instance.Status != FlowStatusWaiting {
+		s.Remove(instanceId)
+	}
 	if instance.isDebug {
 		logs.Debugf("run InstanceId:%s:%s", instanceId, instance.ToJson().ToString())
 	}
@@ -213,20 +453,73 @@ func (s *WorkFlows) run(instanceId, tag string, step int, tags, ctx et.Json, run
 }
 
 /**
-* reset
-* @param instanceId, updatedBy string
-* @return error
+* resumeParent
+* Reanuda el padre en espera cuando la instancia hija termina o falla definitivamente
+* @param child *Instance, runBy string
 **/
-func (s *WorkFlows) reset(instanceId, updatedBy string) error {
-	instance, exists := s.loadInstance(instanceId)
+func (s *WorkFlows) resumeParent(child *Instance, runBy string) {
+	if child.Status != FlowStatusDone && !child.isFailed() {
+		return
+	}
+
+	parent, exists := s.loadInstance(child.ParentId)
 	if !exists {
-		return fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
+		return
 	}
 
-	instance.UpdatedBy = updatedBy
-	instance.SetStatus(FlowStatusPending)
+	if parent.Status != FlowStatusWaiting {
+		return
+	}
 
-	return nil
+	_, err := s.run(parent.Id, parent.Tag, parent.Current, et.Json{}, et.Json{}, runBy)
+	if err != nil {
+		logs.Error(err)
+	}
+}
+
+/**
+* transition
+* Aplica fn sobre la instancia con su bloqueo y lease, si otro worker la guardo antes (ConflictError)
+* se recarga del store y se vuelve a aplicar. Solo para transiciones que no dependen del estado anterior
+* @param instanceId string, fn func(instance *Instance) error
+* @return error
+**/
+func (s *WorkFlows) transition(instanceId string, fn func(instance *Instance) error) error {
+	for attempt := 1; ; attempt++ {
+		instance, exists := s.loadInstance(instanceId)
+		if !exists {
+			return fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
+		}
+
+		unlock, err := s.acquire(instance)
+		if err != nil {
+			return err
+		}
+
+		err = fn(instance)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || attempt >= conflictRetries {
+			unlock()
+			return err
+		}
+
+		logs.Logf(packageName, MSG_INSTANCE_CONFLICT_RETRY, instanceId, attempt)
+		s.Remove(instance.Id)
+		unlock()
+	}
+}
+
+/**
+* reset
+* @param instanceId, updatedBy string
+* @return error
+**/
+func (s *WorkFlows) reset(instanceId, updatedBy string) error {
+	return s.transition(instanceId, func(instance *Instance) error {
+		instance.UpdatedBy = updatedBy
+		instance.addHistory(HistoryReset, "")
+		return instance.SetStatus(FlowStatusPending)
+	})
 }
 
 /**
@@ -240,7 +533,18 @@ func (s *WorkFlows) rollback(instanceId string) (et.Json, error) {
 		return et.Json{}, fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
 	}
 
-	result, err := instance.rollback(et.Json{}, nil)
+	switch instance.Status {
+	case FlowStatusPending, FlowStatusRunning, FlowStatusDone, FlowStatusRolledBack:
+		return et.Json{}, fmt.Errorf(MSG_INSTANCE_ROLLBACK_STATUS, instance.Status)
+	}
+
+	unlock, err := s.acquire(instance)
+	if err != nil {
+		return et.Json{}, err
+	}
+	defer unlock()
+
+	result, err := instance.compensate()
 	if err != nil {
 		return et.Json{}, err
 	}
@@ -249,17 +553,40 @@ func (s *WorkFlows) rollback(instanceId string) (et.Json, error) {
 }
 
 /**
-* stop
+* cancel
+* Si la instancia esta en ejecucion se cancela su contexto, si no se marca como cancelada
 * @param instanceId string
 * @return error
 **/
-func (s *WorkFlows) stop(instanceId string) error {
+func (s *WorkFlows) cancel(instanceId string) error {
 	instance, exists := s.loadInstance(instanceId)
 	if !exists {
 		return fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
 	}
 
-	return instance.Stop()
+	if instance.interrupt() {
+		return nil
+	}
+
+	return s.transition(instanceId, func(instance *Instance) error {
+		if instance.Status == FlowStatusDone {
+			return fmt.Errorf(MSG_INSTANCE_ALREADY_DONE)
+		}
+
+		s.timers.cancel(instanceId)
+		return instance.SetStatus(FlowStatusCancelled)
+	})
+}
+
+/**
+* stop
+* @param instanceId string
+* @return error
+**/
+func (s *WorkFlows) stop(instanceId string) error {
+	return s.transition(instanceId, func(instance *Instance) error {
+		return instance.Stop()
+	})
 }
 
 /**
@@ -273,10 +600,13 @@ func (s *WorkFlows) delete(instanceId string) error {
 		return fmt.Errorf(MSG_INSTANCE_NOT_FOUND)
 	}
 
-	if deleteFn != nil {
-		deleteFn(instanceId)
+	err := getStore().DeleteInstance(instanceId)
+	if err != nil {
+		return err
 	}
 
+	s.timers.cancel(instanceId)
+
 	s.Remove(instanceId)
 	event.Publish(EVENT_WORKFLOW_DELETE, instance.ToJson())
 
@@ -288,6 +618,9 @@ func (s *WorkFlows) delete(instanceId string) error {
 * @param flow *Flow
 **/
 func (s *WorkFlows) add(flow *Flow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.Flows[flow.Tag] = flow
 }
 
@@ -321,17 +654,20 @@ func (s *WorkFlows) newFlowDefinition(tag, version, name, description string, de
 * @return error
 **/
 func (s *WorkFlows) deleteFlow(tag string) error {
-	if deleteFlow != nil {
-		deleteFlow(tag)
+	err := getStore().DeleteFlow(tag)
+	if err != nil {
+		return err
 	}
 
-	if s.Flows[tag] == nil {
+	s.mu.Lock()
+	flow := s.Flows[tag]
+	delete(s.Flows, tag)
+	s.mu.Unlock()
+	if flow == nil {
 		return nil
 	}
 
-	flow := s.Flows[tag]
 	event.Publish(EVENT_FLOW_DELETE, flow.ToJson())
-	delete(s.Flows, tag)
 
 	return nil
 }
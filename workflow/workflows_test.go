@@ -0,0 +1,141 @@
+package workflow
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgalvisleon/et/et"
+)
+
+/**
+* testWorkFlows
+* Motor sin cache ni eventos para las pruebas de ejecucion, con store nil se usan las funciones On*
+* @param t *testing.T, store Store
+* @return *WorkFlows
+**/
+func testWorkFlows(t *testing.T, store Store) *WorkFlows {
+	t.Helper()
+
+	SetStore(store)
+	workFlows = newWorkFlows()
+	t.Cleanup(func() {
+		workFlows = nil
+		SetStore(nil)
+	})
+
+	return workFlows
+}
+
+/**
+* testHooks
+* Funciones OnGet, OnSet y OnList sobre un mapa, OnSet compara la revision como lo haria un backend
+* @param t *testing.T
+**/
+func testHooks(t *testing.T) {
+	t.Helper()
+
+	instances := make(map[string][]byte)
+	mu := sync.Mutex{}
+	OnGet(func(id string) (*Instance, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		bt, ok := instances[id]
+		if !ok {
+			return nil, errorInstanceNotFound
+		}
+
+		var result *Instance
+		err := json.Unmarshal(bt, &result)
+		return result, err
+	})
+	OnSet(func(instance *Instance) error {
+		bt, err := instance.Serialize()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		current := int64(0)
+		if prev, ok := instances[instance.Id]; ok {
+			var stored struct {
+				Revision int64 `json:"revision"`
+			}
+			json.Unmarshal(prev, &stored)
+			current = stored.Revision
+		}
+		if current != instance.Revision-1 {
+			return &ConflictError{InstanceId: instance.Id, Expected: instance.Revision - 1, Current: current}
+		}
+
+		instances[instance.Id] = bt
+		return nil
+	})
+	OnList(func(query Query) ([]*Instance, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		result := make([]*Instance, 0)
+		for _, bt := range instances {
+			var instance *Instance
+			err := json.Unmarshal(bt, &instance)
+			if err != nil {
+				return nil, err
+			}
+
+			if query.match(instance) {
+				result = append(result, instance)
+			}
+		}
+
+		return result, nil
+	})
+	t.Cleanup(func() {
+		getFn = nil
+		setFn = nil
+		listFn = nil
+	})
+}
+
+/**
+* testStep
+* Step Go que agrega value a ctx en key
+* @param key string, value any
+* @return FnContext
+**/
+func testStep(key string, value any) FnContext {
+	return func(flow *Instance, ctx et.Json) (et.Json, error) {
+		return et.Json{key: value}, nil
+	}
+}
+
+/**
+* waitStatus
+* Espera a que la instancia guardada tenga status
+* @param t *testing.T, instanceId string, status FlowStatus
+* @return *Instance
+**/
+func waitStatus(t *testing.T, instanceId string, status FlowStatus) *Instance {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, err := getStore().GetInstance(instanceId)
+		if err == nil && result.Status == status {
+			return result
+		}
+
+		if time.Now().After(deadline) {
+			current := FlowStatus("")
+			if result != nil {
+				current = result.Status
+			}
+			t.Fatalf("instance %s: expected status %s, got %s (%v)", instanceId, status, current, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}